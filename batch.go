@@ -0,0 +1,143 @@
+package nomaddiffprinter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// defaultPlanConcurrency is the number of plans PlanAll submits at a time
+	// when no concurrency is configured.
+	defaultPlanConcurrency = 4
+
+	// planErrorExitCode is the exit code of a job whose plan failed, matching
+	// `nomad job plan`.
+	planErrorExitCode = 255
+)
+
+// PlanAllOptions configures PlanAll.
+type PlanAllOptions struct {
+	// Concurrency is the maximum number of plans in flight. If zero, 4 plans
+	// are submitted at a time.
+	Concurrency int
+
+	// PlanOptions configures the plan of each job.
+	PlanOptions *PlanOptions
+
+	// PrintOptions configures how each plan is printed.
+	PrintOptions *PrintOptions
+}
+
+// PlanResult is the outcome of planning a single job with PlanAll.
+type PlanResult struct {
+	Job      *api.Job
+	Response *api.JobPlanResponse
+	Err      error
+
+	// ExitCode is 0 if the plan makes no changes, 1 if it does and 255 if the
+	// plan failed, including when its warnings are treated as errors.
+	ExitCode int
+}
+
+// PlanAll plans the jobs concurrently, each in its own region and namespace,
+// and prints a report with a section per job followed by a summary table. The
+// jobs are printed in the order given regardless of when their plans return.
+// A failed plan does not stop the others; its error is recorded in its result.
+// The combined exit code is the highest of the exit codes of the jobs.
+func PlanAll(ctx context.Context, client *api.Client, jobs []*api.Job, opts *PlanAllOptions, output io.Writer) ([]*PlanResult, int) {
+	concurrency := defaultPlanConcurrency
+	var planOpts *PlanOptions
+	var printOpts *PrintOptions
+	if opts != nil {
+		if opts.Concurrency > 0 {
+			concurrency = opts.Concurrency
+		}
+		planOpts = opts.PlanOptions
+		printOpts = opts.PrintOptions
+	}
+
+	// Each job is printed to its own buffer, since validation errors are
+	// printed as soon as they are returned.
+	results := make([]*PlanResult, len(jobs))
+	outputs := make([]bytes.Buffer, len(jobs))
+	work := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range work {
+				resp, err := PlanAndPrintDiffContext(ctx, client, jobs[j], planOpts, printOpts, &outputs[j])
+				results[j] = &PlanResult{Job: jobs[j], Response: resp, Err: err}
+			}
+		}()
+	}
+	for i := range jobs {
+		work <- i
+	}
+	close(work)
+	wg.Wait()
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	var exitCode int
+	for i, result := range results {
+		print(colorize().Color(fmt.Sprintf("[bold]==> Job: %q[reset]\n", stringValue(result.Job.ID))))
+		outputs[i].WriteTo(output)
+
+		// The preemptions table is the only section not followed by a blank
+		// line.
+		if result.Response != nil {
+			if a := result.Response.Annotations; a != nil && len(a.PreemptedAllocs) > 0 {
+				print("")
+			}
+		}
+
+		if result.Err != nil {
+			result.ExitCode = planErrorExitCode
+			print(colorize().Color(fmt.Sprintf("[bold][red]Error during plan: %s[reset]\n", result.Err)))
+		} else {
+			result.ExitCode = getExitCode(result.Response)
+		}
+		if result.ExitCode > exitCode {
+			exitCode = result.ExitCode
+		}
+	}
+
+	print(colorize().Color("[bold]Summary:[reset]"))
+	print(colorize().Color(fitTable(formatPlanSummary(results), printOpts)))
+	return results, exitCode
+}
+
+// formatPlanSummary produces a table with a row of update counts per job.
+func formatPlanSummary(results []*PlanResult) string {
+	rows := []string{"Job|Namespace|Region|Place|Stop|In-Place|Destructive|Failed Groups|Result"}
+	for _, result := range results {
+		job := result.Job
+		if result.Err != nil {
+			rows = append(rows, fmt.Sprintf("%s|%s|%s|-|-|-|-|-|[red]error[reset]",
+				stringValue(job.ID), stringValue(job.Namespace), stringValue(job.Region)))
+			continue
+		}
+
+		counts := getPlanCounts(result.Response)
+		outcome := "[green]no changes[reset]"
+		switch {
+		case counts.FailedGroups > 0:
+			outcome = "[yellow]placement failures[reset]"
+		case result.ExitCode != 0:
+			outcome = "[light_yellow]changes[reset]"
+		}
+		rows = append(rows, fmt.Sprintf("%s|%s|%s|%d|%d|%d|%d|%d|%s",
+			stringValue(job.ID), stringValue(job.Namespace), stringValue(job.Region),
+			counts.Places, counts.Stops, counts.InPlaceUpdates, counts.DestructiveUpdates,
+			counts.FailedGroups, outcome))
+	}
+	return formatList(rows)
+}
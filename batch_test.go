@@ -0,0 +1,109 @@
+package nomaddiffprinter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestPlanAllPlanOptions(t *testing.T) {
+	var mu sync.Mutex
+	var validated []string
+	overrides := make(map[string]bool)
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v1/validate/job":
+			var req api.JobValidateRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			validated = append(validated, *req.Job.ID)
+			mu.Unlock()
+
+			resp := &api.JobValidateResponse{}
+			if *req.Job.ID == "invalid" {
+				resp.ValidationErrors = []string{"missing datacenters"}
+			}
+			json.NewEncoder(w).Encode(resp)
+
+		case strings.HasSuffix(r.URL.Path, "/plan"):
+			var req api.JobPlanRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			mu.Lock()
+			overrides[*req.Job.ID] = req.PolicyOverride
+			mu.Unlock()
+
+			resp := &api.JobPlanResponse{
+				Diff:        &api.JobDiff{Type: diffTypeNone, ID: *req.Job.ID},
+				Annotations: &api.PlanAnnotations{},
+			}
+			if *req.Job.ID == "deprecated" {
+				resp.Warnings = "1 warning(s):\n\n* The update stanza is deprecated"
+			}
+			json.NewEncoder(w).Encode(resp)
+
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+
+	jobs := []*api.Job{
+		testJob("valid", "default"),
+		testJob("invalid", "default"),
+		testJob("deprecated", "default"),
+	}
+	opts := &PlanAllOptions{
+		PlanOptions: &PlanOptions{
+			Validate:         true,
+			PolicyOverride:   true,
+			WarningsAsErrors: []WarningCategory{WarningDeprecation},
+		},
+	}
+	var out bytes.Buffer
+	results, exitCode := PlanAll(context.Background(), client, jobs, opts, &out)
+
+	if len(validated) != 3 {
+		t.Errorf("expected every job to be validated, got %q", validated)
+	}
+	if len(overrides) != 2 || !overrides["valid"] || !overrides["deprecated"] {
+		t.Errorf("expected the policy override on the plans of the valid jobs, got %v", overrides)
+	}
+
+	if err := results[0].Err; err != nil {
+		t.Errorf("unexpected error planning valid job: %v", err)
+	}
+	var validationErr *ValidationError
+	if !errors.As(results[1].Err, &validationErr) {
+		t.Errorf("expected a *ValidationError, got %T: %v", results[1].Err, results[1].Err)
+	}
+	var warningErr *WarningError
+	if !errors.As(results[2].Err, &warningErr) {
+		t.Errorf("expected a *WarningError, got %T: %v", results[2].Err, results[2].Err)
+	}
+	if results[2].Response == nil {
+		t.Errorf("expected the response of a plan with warnings treated as errors")
+	}
+
+	expectedCodes := []int{0, planErrorExitCode, planErrorExitCode}
+	for i, result := range results {
+		if result.ExitCode != expectedCodes[i] {
+			t.Errorf("job %q exited with %d, expected %d", *result.Job.ID, result.ExitCode, expectedCodes[i])
+		}
+	}
+	if exitCode != planErrorExitCode {
+		t.Errorf("expected exit code %d, got %d", planErrorExitCode, exitCode)
+	}
+
+	// The validation errors are printed in the section of their job.
+	report := stripColor(out.String())
+	section := report[strings.Index(report, `==> Job: "invalid"`):strings.Index(report, `==> Job: "deprecated"`)]
+	if !strings.Contains(section, "missing datacenters") {
+		t.Errorf("expected the validation errors in the section of the job:\n%s", report)
+	}
+}
@@ -0,0 +1,522 @@
+package nomaddiffprinter
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/colorstring"
+	"golang.org/x/term"
+)
+
+// Keys understood by the browser. The arrow keys are negative so that they
+// never collide with a rune.
+const (
+	keyUp = -(iota + 1)
+	keyDown
+	keyLeft
+	keyRight
+	keyEnter  = '\r'
+	keyEscape = 0x1b
+	keyDelete = 0x7f
+)
+
+// browserNode is a line of the plan tree that can be folded when it has
+// children.
+type browserNode struct {
+	label       string
+	name        string
+	destructive bool
+	folded      bool
+	depth       int
+	parent      *browserNode
+	children    []*browserNode
+}
+
+func (n *browserNode) add(child *browserNode) *browserNode {
+	child.parent = n
+	child.depth = n.depth + 1
+	n.children = append(n.children, child)
+	if child.destructive {
+		for p := n; p != nil; p = p.parent {
+			p.destructive = true
+		}
+	}
+	return child
+}
+
+// Browser is an interactive terminal browser of a plan. The job diff can be
+// navigated and folded, filtered to destructive changes only and searched by
+// name. It reads keys from in and draws to out so that it can be driven
+// without a real terminal.
+type Browser struct {
+	in            *bufio.Reader
+	out           io.Writer
+	width, height int
+	color         *colorstring.Colorize
+
+	root        []*browserNode
+	failures    *browserNode
+	preemptions *browserNode
+
+	cursor          int
+	offset          int
+	destructiveOnly bool
+	searching       bool
+	query           string
+	message         string
+}
+
+// NewBrowser returns a browser of the plan that reads keys from in and draws a
+// width by height screen to out.
+func NewBrowser(job *api.Job, resp *api.JobPlanResponse, in io.Reader, out io.Writer, width, height int) *Browser {
+	b := &Browser{
+		in:     bufio.NewReader(in),
+		out:    out,
+		width:  width,
+		height: height,
+		color:  colorize(),
+	}
+	b.build(job, resp)
+	return b
+}
+
+// BrowsePlan runs an interactive browser of the plan on the terminal attached
+// to stdin and stdout.
+func BrowsePlan(job *api.Job, resp *api.JobPlanResponse) error {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return fmt.Errorf("plan browser requires a terminal")
+	}
+	width, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return err
+	}
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+
+	// Use the alternate screen so the terminal is left untouched on exit.
+	fmt.Fprint(os.Stdout, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(os.Stdout, "\x1b[?25h\x1b[?1049l")
+
+	return NewBrowser(job, resp, os.Stdin, os.Stdout, width, height).Run()
+}
+
+// Run draws the browser and handles keys until q is pressed or the input is
+// exhausted.
+func (b *Browser) Run() error {
+	for {
+		b.draw()
+		key, err := b.readKey()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !b.handleKey(key) {
+			return nil
+		}
+	}
+}
+
+// View returns the current screen without color or cursor escapes.
+func (b *Browser) View() string {
+	return stripColor(strings.Join(b.screen(), "\n"))
+}
+
+// build creates the tree from the plan, walking the diff in the same order as
+// formatJobDiff and using the same line formatters.
+func (b *Browser) build(job *api.Job, resp *api.JobPlanResponse) {
+	if d := resp.Diff; d != nil {
+		marker, _ := getDiffString(d.Type)
+		jobNode := &browserNode{label: fmt.Sprintf("%s[bold]Job: %q[reset]", marker, d.ID), name: d.ID}
+		b.root = append(b.root, jobNode)
+		addBrowserFieldsAndObjects(jobNode, d.Fields, d.Objects)
+
+		for _, tg := range d.TaskGroups {
+			marker, _ := getDiffString(tg.Type)
+			tgNode := jobNode.add(&browserNode{
+				label:       fmt.Sprintf("%s[bold]Task Group: %q[reset]%s", marker, tg.Name, formatTaskGroupUpdates(tg.Updates)),
+				name:        tg.Name,
+				destructive: tg.Updates[schedulerUpdateTypeDestructiveUpdate]+tg.Updates[schedulerUpdateTypeDestroy] > 0,
+			})
+			addBrowserFieldsAndObjects(tgNode, tg.Fields, tg.Objects)
+
+			for _, task := range tg.Tasks {
+				marker, _ := getDiffString(task.Type)
+				label := fmt.Sprintf("%s[bold]Task: %q[reset]", marker, task.Name)
+				if len(task.Annotations) != 0 {
+					label += fmt.Sprintf(" (%s)", colorAnnotations(task.Annotations))
+				}
+				taskNode := tgNode.add(&browserNode{
+					label:       label,
+					name:        task.Name,
+					destructive: isDestructive(task.Annotations),
+				})
+				addBrowserFieldsAndObjects(taskNode, task.Fields, task.Objects)
+			}
+		}
+	}
+
+	dryRun := &browserNode{label: "[bold]Scheduler dry-run[reset]", name: "Scheduler dry-run"}
+	b.root = append(b.root, dryRun)
+	for _, line := range strings.Split(formatDryRun(resp, job), "\n") {
+		if strings.TrimSpace(line) != "" {
+			dryRun.add(&browserNode{label: strings.TrimLeft(line, " ")})
+		}
+	}
+
+	if len(resp.FailedTGAllocs) > 0 {
+		b.failures = &browserNode{label: "[bold][yellow]Placement failures[reset]", name: "Placement failures", destructive: true}
+		b.root = append(b.root, b.failures)
+		for _, tg := range sortedTaskGroupFromMetrics(resp.FailedTGAllocs) {
+			metrics := resp.FailedTGAllocs[tg]
+			noun := "allocation"
+			if metrics.CoalescedFailures > 0 {
+				noun += "s"
+			}
+			tgNode := b.failures.add(&browserNode{
+				label:       fmt.Sprintf("[yellow]Task Group %q (failed to place %d %s)[reset]", tg, metrics.CoalescedFailures+1, noun),
+				name:        tg,
+				destructive: true,
+			})
+			for _, line := range strings.Split(formatAllocMetrics(metrics, false, ""), "\n") {
+				tgNode.add(&browserNode{label: "[yellow]" + line + "[reset]", destructive: true})
+			}
+		}
+	}
+
+	if resp.Annotations != nil && len(resp.Annotations.PreemptedAllocs) > 0 {
+		b.preemptions = &browserNode{label: "[bold][yellow]Preemptions[reset]", name: "Preemptions", destructive: true}
+		b.root = append(b.root, b.preemptions)
+		// Sort a copy so that the response is left as it was returned.
+		allocs := make([]*api.AllocationListStub, len(resp.Annotations.PreemptedAllocs))
+		copy(allocs, resp.Annotations.PreemptedAllocs)
+		sort.Slice(allocs, func(i, j int) bool { return allocs[i].ID < allocs[j].ID })
+		for _, alloc := range allocs {
+			b.preemptions.add(&browserNode{
+				label:       fmt.Sprintf("%s (job %q, task group %q)", alloc.ID, alloc.JobID, alloc.TaskGroup),
+				name:        alloc.ID,
+				destructive: true,
+			})
+		}
+	}
+
+	if resp.Warnings != "" {
+		warnings := &browserNode{label: "[bold][yellow]Job Warnings[reset]", name: "Job Warnings"}
+		b.root = append(b.root, warnings)
+		for _, line := range strings.Split(strings.TrimSpace(resp.Warnings), "\n") {
+			if line != "" {
+				warnings.add(&browserNode{label: "[yellow]" + line + "[reset]"})
+			}
+		}
+	}
+}
+
+// addBrowserFieldsAndObjects adds the fields and objects as children of the
+// node, aligned the same way as alignedFieldAndObjects.
+func addBrowserFieldsAndObjects(node *browserNode, fields []*api.FieldDiff, objects []*api.ObjectDiff) {
+	longestField, longestMarker := getLongestPrefixes(fields, objects)
+	walkAlignedFieldsAndObjects(fields, objects, longestField, longestMarker,
+		func(field *api.FieldDiff, keyPrefix, valuePrefix int) {
+			node.add(&browserNode{
+				label:       formatFieldDiff(field, 0, keyPrefix, valuePrefix, nil),
+				name:        field.Name,
+				destructive: isDestructive(field.Annotations),
+			})
+		},
+		func(object *api.ObjectDiff, keyPrefix int) {
+			marker, _ := getDiffString(object.Type)
+			objNode := node.add(&browserNode{
+				label: fmt.Sprintf("%s%s%s", marker, strings.Repeat(" ", keyPrefix), object.Name),
+				name:  object.Name,
+			})
+			addBrowserFieldsAndObjects(objNode, object.Fields, object.Objects)
+		})
+}
+
+// isDestructive returns whether the annotations force allocations to be
+// destroyed.
+func isDestructive(annotations []string) bool {
+	for _, a := range annotations {
+		if a == "forces destroy" || a == "forces create/destroy update" {
+			return true
+		}
+	}
+	return false
+}
+
+// visible returns the nodes that are displayed given the folds and filter.
+func (b *Browser) visible() []*browserNode {
+	var out []*browserNode
+	var walk func(nodes []*browserNode)
+	walk = func(nodes []*browserNode) {
+		for _, n := range nodes {
+			if b.destructiveOnly && !n.destructive {
+				continue
+			}
+			out = append(out, n)
+			if !n.folded {
+				walk(n.children)
+			}
+		}
+	}
+	walk(b.root)
+	return out
+}
+
+// screen renders the lines of the current screen.
+func (b *Browser) screen() []string {
+	nodes := b.visible()
+	rows := b.height - 1
+	if rows < 1 {
+		rows = 1
+	}
+	if b.cursor >= len(nodes) {
+		b.cursor = len(nodes) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	} else if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+
+	var lines []string
+	for i := b.offset; i < len(nodes) && i < b.offset+rows; i++ {
+		n := nodes[i]
+		cursor := "  "
+		if i == b.cursor {
+			cursor = "[bold]>[reset] "
+		}
+		fold := "  "
+		if len(n.children) > 0 {
+			fold = "▾ "
+			if n.folded {
+				fold = "▸ "
+			}
+		}
+		label := n.label
+		if b.query != "" && b.matches(n) {
+			label = "[underline]" + label
+		}
+		line := cursor + strings.Repeat("  ", n.depth) + fold + label
+		lines = append(lines, fitLine(line, b.width))
+	}
+	for len(lines) < rows {
+		lines = append(lines, "")
+	}
+	return append(lines, fitLine(b.status(), b.width))
+}
+
+// status returns the status line.
+func (b *Browser) status() string {
+	if b.searching {
+		return "/" + b.query
+	}
+	if b.message != "" {
+		return "[yellow]" + b.message + "[reset]"
+	}
+	filter := "off"
+	if b.destructiveOnly {
+		filter = "on"
+	}
+	return fmt.Sprintf("[invert]j/k move  enter fold  d destructive only (%s)  / search  n next  f failures  p preemptions  q quit[reset]", filter)
+}
+
+// draw writes the current screen to the output.
+func (b *Browser) draw() {
+	lines := b.screen()
+	for i := range lines {
+		lines[i] = b.color.Color(lines[i]) + "\x1b[K"
+	}
+	fmt.Fprint(b.out, "\x1b[H"+strings.Join(lines, "\r\n"))
+}
+
+// handleKey applies a key and returns false when the browser should exit.
+func (b *Browser) handleKey(key int) bool {
+	b.message = ""
+	if b.searching {
+		switch key {
+		case keyEnter:
+			b.searching = false
+			b.next()
+		case keyEscape:
+			b.searching = false
+			b.query = ""
+		case keyDelete, '\b':
+			if b.query != "" {
+				b.query = string([]rune(b.query)[:len([]rune(b.query))-1])
+			}
+		default:
+			if key >= 0 && unicode.IsPrint(rune(key)) {
+				b.query += string(rune(key))
+			}
+		}
+		return true
+	}
+
+	nodes := b.visible()
+	switch key {
+	case 'q', 3: // ctrl-c
+		return false
+	case 'j', keyDown:
+		b.cursor++
+	case 'k', keyUp:
+		b.cursor--
+	case 'g':
+		b.cursor = 0
+	case 'G':
+		b.cursor = len(nodes) - 1
+	case keyEnter, ' ':
+		if n := b.current(nodes); n != nil && len(n.children) > 0 {
+			n.folded = !n.folded
+		}
+	case 'h', keyLeft:
+		if n := b.current(nodes); n != nil {
+			if len(n.children) > 0 && !n.folded {
+				n.folded = true
+			} else if n.parent != nil {
+				b.moveTo(n.parent)
+			}
+		}
+	case 'l', keyRight:
+		if n := b.current(nodes); n != nil && n.folded {
+			n.folded = false
+		}
+	case 'd':
+		b.destructiveOnly = !b.destructiveOnly
+		b.cursor = 0
+	case '/':
+		b.searching = true
+		b.query = ""
+	case 'n':
+		b.next()
+	case 'f':
+		b.jump(b.failures, "No placement failures")
+	case 'p':
+		b.jump(b.preemptions, "No preemptions")
+	}
+	return true
+}
+
+// current returns the node under the cursor.
+func (b *Browser) current(nodes []*browserNode) *browserNode {
+	if b.cursor < 0 || b.cursor >= len(nodes) {
+		return nil
+	}
+	return nodes[b.cursor]
+}
+
+// moveTo unfolds the ancestors of the node and moves the cursor to it.
+func (b *Browser) moveTo(target *browserNode) bool {
+	for p := target.parent; p != nil; p = p.parent {
+		p.folded = false
+	}
+	for i, n := range b.visible() {
+		if n == target {
+			b.cursor = i
+			return true
+		}
+	}
+	return false
+}
+
+// jump moves the cursor to a section, or shows msg if there is none.
+func (b *Browser) jump(target *browserNode, msg string) {
+	if target == nil || !b.moveTo(target) {
+		b.message = msg
+	}
+}
+
+// next moves the cursor to the next node after it whose name matches the
+// search query, wrapping around to the top.
+func (b *Browser) next() {
+	if b.query == "" {
+		return
+	}
+
+	var all []*browserNode
+	var walk func(nodes []*browserNode)
+	walk = func(nodes []*browserNode) {
+		for _, n := range nodes {
+			if !b.destructiveOnly || n.destructive {
+				all = append(all, n)
+				walk(n.children)
+			}
+		}
+	}
+	walk(b.root)
+
+	// Find the position of the cursor in the unfolded tree to search from.
+	start := 0
+	if cur := b.current(b.visible()); cur != nil {
+		for i, n := range all {
+			if n == cur {
+				start = i + 1
+			}
+		}
+	}
+	for i := 0; i < len(all); i++ {
+		if n := all[(start+i)%len(all)]; b.matches(n) {
+			b.moveTo(n)
+			return
+		}
+	}
+	b.message = fmt.Sprintf("No match for %q", b.query)
+}
+
+// matches returns whether the node's name contains the search query.
+func (b *Browser) matches(n *browserNode) bool {
+	return n.name != "" && strings.Contains(strings.ToLower(n.name), strings.ToLower(b.query))
+}
+
+// readKey reads a key, decoding the escape sequences of the arrow keys.
+func (b *Browser) readKey() (int, error) {
+	r, _, err := b.in.ReadRune()
+	if err != nil {
+		return 0, err
+	}
+	if r == '\n' {
+		return keyEnter, nil
+	}
+	if r != keyEscape || b.in.Buffered() < 2 {
+		return int(r), nil
+	}
+	if next, _ := b.in.Peek(1); next[0] != '[' {
+		return int(r), nil
+	}
+	b.in.ReadByte()
+	c, _ := b.in.ReadByte()
+	switch c {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	}
+	return int(r), nil
+}
+
+// fitLine cuts a colorized line to width columns. Lines that are cut lose
+// their colors.
+func fitLine(line string, width int) string {
+	if width <= 0 || displayWidth(line) <= width {
+		return line
+	}
+	head, _ := cutWidth(stripColor(line), width-1)
+	return head + "…"
+}
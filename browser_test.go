@@ -0,0 +1,184 @@
+package nomaddiffprinter
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// browserTestPlan returns a plan with a destructive task update, a
+// non-destructive task group change and two preemptions.
+func browserTestPlan() (*api.Job, *api.JobPlanResponse) {
+	job := testJob("example", "default")
+	resp := &api.JobPlanResponse{
+		Diff: &api.JobDiff{
+			Type: "Edited",
+			ID:   "example",
+			TaskGroups: []*api.TaskGroupDiff{{
+				Type:    "Edited",
+				Name:    "web",
+				Updates: map[string]uint64{schedulerUpdateTypeDestructiveUpdate: 1},
+				Fields: []*api.FieldDiff{
+					{Type: "Edited", Name: "Count", Old: "1", New: "2"},
+				},
+				Tasks: []*api.TaskDiff{{
+					Type:        "Edited",
+					Name:        "app",
+					Annotations: []string{"forces create/destroy update"},
+					Objects: []*api.ObjectDiff{{
+						Type: "Edited",
+						Name: "Config",
+						Fields: []*api.FieldDiff{
+							{Type: "Edited", Name: "image", Old: "redis:6", New: "redis:7"},
+						},
+					}},
+				}},
+			}},
+		},
+		Annotations: &api.PlanAnnotations{
+			DesiredTGUpdates: map[string]*api.DesiredUpdates{"web": {DestructiveUpdate: 1}},
+			PreemptedAllocs: []*api.AllocationListStub{
+				{ID: "bbbbbbbb", JobID: "batch", TaskGroup: "worker"},
+				{ID: "aaaaaaaa", JobID: "batch", TaskGroup: "worker"},
+			},
+		},
+	}
+	return job, resp
+}
+
+// runBrowser drives a browser of the plan with the keys and returns its view.
+func runBrowser(t *testing.T, keys string) string {
+	t.Helper()
+	job, resp := browserTestPlan()
+	b := NewBrowser(job, resp, strings.NewReader(keys), ioutil.Discard, 80, 30)
+	if err := b.Run(); err != nil {
+		t.Fatalf("error running browser: %v", err)
+	}
+	return b.View()
+}
+
+// cursorLine returns the line of the view under the cursor.
+func cursorLine(view string) string {
+	for _, line := range strings.Split(view, "\n") {
+		if strings.HasPrefix(line, "> ") {
+			return line
+		}
+	}
+	return ""
+}
+
+func TestBrowserView(t *testing.T) {
+	view := runBrowser(t, "")
+	for _, expected := range []string{
+		`> ▾ +/- Job: "example"`,
+		`Task Group: "web"`,
+		`Count: "1" => "2"`,
+		`Task: "app" (forces create/destroy update)`,
+		`image: "redis:6" => "redis:7"`,
+		"Scheduler dry-run",
+		"j/k move",
+	} {
+		if !strings.Contains(view, expected) {
+			t.Errorf("expected %q in view:\n%s", expected, view)
+		}
+	}
+	if lines := strings.Split(view, "\n"); len(lines) != 30 {
+		t.Errorf("expected 30 lines, got %d", len(lines))
+	}
+}
+
+func TestBrowserKeys(t *testing.T) {
+	cases := []struct {
+		name, keys  string
+		cursor      string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "fold",
+			keys:        "\r",
+			cursor:      `▸ +/- Job: "example"`,
+			notContains: []string{`Task Group: "web"`},
+		},
+		{
+			name:   "arrow keys",
+			keys:   "\x1b[B\x1b[B\x1b[A",
+			cursor: `Task Group: "web"`,
+		},
+		{
+			name:        "destructive only",
+			keys:        "d",
+			contains:    []string{`Task: "app"`, "destructive only (on)", "Preemptions"},
+			notContains: []string{"Count", "Scheduler dry-run"},
+		},
+		{
+			name:   "search",
+			keys:   "/image\r",
+			cursor: `image: "redis:6" => "redis:7"`,
+		},
+		{
+			name:     "search without match",
+			keys:     "/missing\r",
+			contains: []string{`No match for "missing"`},
+		},
+		{
+			name:     "search for wide characters",
+			keys:     "/日本語🚀\r",
+			contains: []string{`No match for "日本語🚀"`},
+		},
+		{
+			name:   "fold and search",
+			keys:   "\r/app\r",
+			cursor: `Task: "app"`,
+		},
+		{
+			name:   "preemptions",
+			keys:   "pj",
+			cursor: "aaaaaaaa",
+		},
+		{
+			name:     "no failures",
+			keys:     "f",
+			contains: []string{"No placement failures"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			view := runBrowser(t, c.keys)
+			if c.cursor != "" && !strings.Contains(cursorLine(view), c.cursor) {
+				t.Errorf("expected the cursor on %q, got %q:\n%s", c.cursor, cursorLine(view), view)
+			}
+			for _, s := range c.contains {
+				if !strings.Contains(view, s) {
+					t.Errorf("expected %q in view:\n%s", s, view)
+				}
+			}
+			for _, s := range c.notContains {
+				if strings.Contains(view, s) {
+					t.Errorf("unexpected %q in view:\n%s", s, view)
+				}
+			}
+		})
+	}
+}
+
+func TestBrowserQuit(t *testing.T) {
+	// Keys after q are never read.
+	view := runBrowser(t, "qd")
+	if strings.Contains(view, "destructive only (on)") {
+		t.Errorf("keys were handled after quitting:\n%s", view)
+	}
+}
+
+func TestBrowserLeavesResponseUnchanged(t *testing.T) {
+	job, resp := browserTestPlan()
+	NewBrowser(job, resp, strings.NewReader(""), ioutil.Discard, 80, 30)
+
+	allocs := resp.Annotations.PreemptedAllocs
+	if allocs[0].ID != "bbbbbbbb" || allocs[1].ID != "aaaaaaaa" {
+		t.Errorf("the preempted allocations of the response were reordered")
+	}
+}
@@ -0,0 +1,42 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+)
+
+// ContextError is returned when a request to Nomad is abandoned because its
+// context was canceled or its deadline was exceeded.
+type ContextError struct {
+	// Op describes the request that was abandoned.
+	Op string
+
+	// Err is the error of the context, either context.Canceled or
+	// context.DeadlineExceeded.
+	Err error
+}
+
+func (e *ContextError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the error of the context so that errors.Is can be used to
+// tell a cancellation from a timeout.
+func (e *ContextError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns whether the deadline of the context was exceeded.
+func (e *ContextError) Timeout() bool {
+	return e.Err == context.DeadlineExceeded
+}
+
+// contextError returns a ContextError if err was caused by ctx being done and
+// err otherwise. The api package reports a canceled request as a plain error
+// from the HTTP client, so the context is checked instead of err.
+func contextError(ctx context.Context, op string, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return &ContextError{Op: op, Err: ctx.Err()}
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// testClient returns a client of a test server serving handler.
+func testClient(t *testing.T, handler http.Handler) *api.Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	config := api.DefaultConfig()
+	config.Address = srv.URL
+	client, err := api.NewClient(config)
+	if err != nil {
+		t.Fatalf("error creating client: %v", err)
+	}
+	return client
+}
+
+// slowHandler never responds before the request is abandoned by the client.
+func slowHandler(t *testing.T) http.Handler {
+	done := make(chan struct{})
+	t.Cleanup(func() { close(done) })
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client went away once the body is read.
+		io.Copy(ioutil.Discard, r.Body)
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	})
+}
+
+func TestContextErrors(t *testing.T) {
+	job := testJob("example", "default")
+	calls := map[string]func(ctx context.Context, client *api.Client) error{
+		"PlanAndPrintDiffContext": func(ctx context.Context, client *api.Client) error {
+			_, err := PlanAndPrintDiffContext(ctx, client, job, nil, nil, ioutil.Discard)
+			return err
+		},
+		"DetectDriftAndPrintDiffContext": func(ctx context.Context, client *api.Client) error {
+			_, _, err := DetectDriftAndPrintDiffContext(ctx, client, job, ioutil.Discard)
+			return err
+		},
+		"PrintJobHistoryContext": func(ctx context.Context, client *api.Client) error {
+			_, err := PrintJobHistoryContext(ctx, client, "example", nil, ioutil.Discard)
+			return err
+		},
+		"StopPreviewContext": func(ctx context.Context, client *api.Client) error {
+			_, _, err := StopPreviewContext(ctx, client, "example", false, nil, ioutil.Discard)
+			return err
+		},
+		"PlanRevertAndPrintDiffContext": func(ctx context.Context, client *api.Client) error {
+			_, _, err := PlanRevertAndPrintDiffContext(ctx, client, "example", 0, nil, ioutil.Discard)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		call := call
+		t.Run(name+"/deadline", func(t *testing.T) {
+			client := testClient(t, slowHandler(t))
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			err := call(ctx, client)
+			var ctxErr *ContextError
+			if !errors.As(err, &ctxErr) {
+				t.Fatalf("expected a *ContextError, got %T: %v", err, err)
+			}
+			if !ctxErr.Timeout() {
+				t.Errorf("expected a timeout: %v", err)
+			}
+			if !errors.Is(err, context.DeadlineExceeded) {
+				t.Errorf("expected the error to wrap context.DeadlineExceeded: %v", err)
+			}
+		})
+
+		t.Run(name+"/cancel", func(t *testing.T) {
+			client := testClient(t, slowHandler(t))
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			err := call(ctx, client)
+			var ctxErr *ContextError
+			if !errors.As(err, &ctxErr) {
+				t.Fatalf("expected a *ContextError, got %T: %v", err, err)
+			}
+			if ctxErr.Timeout() {
+				t.Errorf("expected a cancellation, not a timeout: %v", err)
+			}
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("expected the error to wrap context.Canceled: %v", err)
+			}
+		})
+	}
+}
+
+// testJob returns a minimal job in the given namespace.
+func testJob(id, namespace string) *api.Job {
+	job := api.NewServiceJob(id, id, "global", 50)
+	job.Namespace = &namespace
+	job.AddDatacenter("dc1")
+	job.AddTaskGroup(api.NewTaskGroup("web", 1).AddTask(api.NewTask("app", "docker")))
+	return job
+}
@@ -1,6 +1,7 @@
 package nomaddiffprinter
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -10,7 +11,6 @@ import (
 
 	"github.com/hashicorp/nomad/api"
 	"github.com/mitchellh/colorstring"
-	"github.com/ryanuber/columnize"
 	"golang.org/x/term"
 )
 
@@ -31,45 +31,89 @@ const (
 	preemptionDisplayThreshold = 10
 )
 
+// PlanAndPrintDiff plans the job and prints its diff and the scheduler
+// dry-run with the default options. Use PlanAndPrintDiffContext to configure
+// the plan or its output.
 func PlanAndPrintDiff(client *api.Client, job *api.Job, output io.Writer) (resp *api.JobPlanResponse, err error) {
-	// Force the region to be that of the job.
-	if r := job.Region; r != nil {
-		client.SetRegion(*r)
+	return PlanAndPrintDiffContext(context.Background(), client, job, nil, nil, output)
+}
+
+// PlanAndPrintDiffContext is like PlanAndPrintDiff but the requests are bound
+// to ctx, planOpts configures the plan and printOpts configures the output.
+// Nil options use the defaults. If ctx is canceled or its deadline passes
+// before the plan returns, a *ContextError is returned.
+func PlanAndPrintDiffContext(ctx context.Context, client *api.Client, job *api.Job, planOpts *PlanOptions, printOpts *PrintOptions, output io.Writer) (resp *api.JobPlanResponse, err error) {
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+
+	if planOpts != nil && planOpts.Validate {
+		if err := validateJob(ctx, client, job, print); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err = planJob(ctx, client, job, planOpts)
+	if err != nil {
+		if policyErr, ok := err.(*PolicyError); ok && len(policyErr.Policies) > 0 {
+			print(colorize().Color(formatPolicyResults(policyErr.Policies, false)))
+		}
+		return nil, err
+	}
+	outputPlannedJob(job, resp, print, true, true, printOpts)
+
+	if planOpts != nil && len(planOpts.WarningsAsErrors) > 0 {
+		warnings := warningsAsErrors(ParseWarnings(resp.Warnings), planOpts.WarningsAsErrors)
+		if len(warnings) > 0 {
+			return resp, &WarningError{JobID: stringValue(job.ID), Warnings: warnings}
+		}
 	}
 
-	// Force the namespace to be that of the job.
+	return resp, err
+}
+
+// writeOptions returns the write options of a request for the job. The
+// region and namespace are forced to be those of the job. They are set on the
+// request rather than the client, which may be shared.
+func writeOptions(ctx context.Context, job *api.Job) *api.WriteOptions {
+	w := &api.WriteOptions{}
+	if r := job.Region; r != nil {
+		w.Region = *r
+	}
 	if n := job.Namespace; n != nil {
-		client.SetNamespace(*n)
+		w.Namespace = *n
 	}
+	return w.WithContext(ctx)
+}
+
+// planJob submits a plan of the job with its diff. A nil planOpts uses the
+// defaults.
+func planJob(ctx context.Context, client *api.Client, job *api.Job, planOpts *PlanOptions) (*api.JobPlanResponse, error) {
+	w := writeOptions(ctx, job)
 
 	// // Setup the options
 	opts := &api.PlanOptions{Diff: true}
 	// if diff {
 	// 	opts.Diff = true
 	// }
-	// if policyOverride {
-	// 	opts.PolicyOverride = true
-	// }
+	if planOpts != nil && planOpts.PolicyOverride {
+		opts.PolicyOverride = true
+	}
 
 	// if job.IsMultiregion() {
-	// 	return c.multiregionPlan(client, job, opts, diff, verbose)
+	// 	return c.multiregionPlan(client, job, opts, w, diff, verbose)
 	// }
 
 	// Submit the job
-	resp, _, err = client.Jobs().PlanOpts(job, opts, nil)
+	resp, _, err := client.Jobs().PlanOpts(job, opts, w)
 	if err != nil {
-		return nil, err
+		return nil, planError(ctx, job, err)
 	}
-	print := func(s string) {
-		fmt.Fprintln(output, s)
-	}
-	outputPlannedJob(job, resp, print, opts.Diff, true)
-
-	return resp, err
+	return resp, nil
 }
 
 // TODO: add multiregion support
-// func multiregionPlan(client *api.Client, job *api.Job, opts *api.PlanOptions, diff, verbose bool) int {
+// func multiregionPlan(client *api.Client, job *api.Job, opts *api.PlanOptions, w *api.WriteOptions, diff, verbose bool) int {
 
 // 	var exitCode int
 // 	plans := map[string]*api.JobPlanResponse{}
@@ -77,10 +121,11 @@ func PlanAndPrintDiff(client *api.Client, job *api.Job, output io.Writer) (resp
 // 	// collect all the plans first so that we can report all errors
 // 	for _, region := range job.Multiregion.Regions {
 // 		regionName := region.Name
-// 		client.SetRegion(regionName)
+// 		regionOpts := *w
+// 		regionOpts.Region = regionName
 
 // 		// Submit the job for this region
-// 		resp, _, err := client.Jobs().PlanOpts(job, opts, nil)
+// 		resp, _, err := client.Jobs().PlanOpts(job, opts, &regionOpts)
 // 		if err != nil {
 // 			c.Ui.Error(fmt.Sprintf("Error during plan for region %q: %s", regionName, err))
 // 			exitCode = 255
@@ -110,11 +155,11 @@ func colorize() *colorstring.Colorize {
 	}
 }
 
-func outputPlannedJob(job *api.Job, resp *api.JobPlanResponse, print func(string), diff, verbose bool) int {
+func outputPlannedJob(job *api.Job, resp *api.JobPlanResponse, print func(string), diff, verbose bool, printOpts *PrintOptions) int {
 	// Print the diff if not disabled
 	if diff {
 		print(fmt.Sprintf("%s\n",
-			colorize().Color(strings.TrimSpace(formatJobDiff(resp.Diff, verbose)))))
+			colorize().Color(strings.TrimSpace(formatDiff(resp.Diff, verbose, printOpts)))))
 	}
 
 	// Print the scheduler dry-run output
@@ -122,22 +167,36 @@ func outputPlannedJob(job *api.Job, resp *api.JobPlanResponse, print func(string
 	print(colorize().Color(formatDryRun(resp, job)))
 	print("")
 
+	// Print the follow-up evaluations if requested
+	if printOpts.verboseDryRun() {
+		print(colorize().Color(formatCreatedEvals(resp.CreatedEvals)))
+		print("")
+	}
+
+	// Print the results of any Sentinel policies that failed but were
+	// overridden. They're left out of the warnings printed below.
+	warnings := ParseWarnings(resp.Warnings)
+	if policies := parsePolicyResults(resp.Warnings); len(policies) > 0 {
+		print(colorize().Color(formatPolicyResults(policies, true)))
+		warnings = withoutCategory(warnings, WarningPolicy)
+	}
+
 	// Print any warnings if there are any
-	if resp.Warnings != "" {
+	if len(warnings) > 0 {
 		print(
-			colorize().Color(fmt.Sprintf("[bold][yellow]Job Warnings:\n%s[reset]\n", resp.Warnings)))
+			colorize().Color(fmt.Sprintf("[bold][yellow]Job Warnings:\n%s[reset]\n", formatWarnings(warnings))))
 	}
 
 	// Print preemptions if there are any
 	if resp.Annotations != nil && len(resp.Annotations.PreemptedAllocs) > 0 {
-		addPreemptions(resp, print)
+		addPreemptions(resp, print, printOpts)
 	}
 
 	return getExitCode(resp)
 }
 
 // addPreemptions shows details about preempted allocations
-func addPreemptions(resp *api.JobPlanResponse, print func(string)) {
+func addPreemptions(resp *api.JobPlanResponse, print func(string), printOpts *PrintOptions) {
 	print(colorize().Color("[bold][yellow]Preemptions:\n[reset]"))
 	if len(resp.Annotations.PreemptedAllocs) < preemptionDisplayThreshold {
 		var allocs []string
@@ -145,7 +204,7 @@ func addPreemptions(resp *api.JobPlanResponse, print func(string)) {
 		for _, alloc := range resp.Annotations.PreemptedAllocs {
 			allocs = append(allocs, fmt.Sprintf("%s|%s|%s", alloc.ID, alloc.JobID, alloc.TaskGroup))
 		}
-		print(formatList(allocs))
+		print(fitTable(formatList(allocs), printOpts))
 		return
 	}
 	// Display in a summary format if the list is too large
@@ -187,14 +246,12 @@ func addPreemptions(resp *api.JobPlanResponse, print func(string)) {
 			outputs = append(outputs, fmt.Sprintf("%s|%d", jobType, total))
 		}
 	}
-	print(formatList(outputs))
+	print(fitTable(formatList(outputs), printOpts))
 
 }
 
 func formatList(in []string) string {
-	columnConf := columnize.DefaultConfig()
-	columnConf.Empty = "<none>"
-	return columnize.Format(in, columnConf)
+	return formatColumns(in, "  ")
 }
 
 type namespaceIdPair struct {
@@ -206,9 +263,13 @@ type namespaceIdPair struct {
 // * 0: No allocations created or destroyed.
 // * 1: Allocations created or destroyed.
 func getExitCode(resp *api.JobPlanResponse) int {
+	if resp.Annotations == nil {
+		return 0
+	}
+
 	// Check for changes
 	for _, d := range resp.Annotations.DesiredTGUpdates {
-		if d.Stop+d.Place+d.Migrate+d.DestructiveUpdate+d.Canary > 0 {
+		if hasUpdates(d) {
 			return 1
 		}
 	}
@@ -216,6 +277,12 @@ func getExitCode(resp *api.JobPlanResponse) int {
 	return 0
 }
 
+// hasUpdates returns whether the desired updates create or destroy
+// allocations.
+func hasUpdates(d *api.DesiredUpdates) bool {
+	return d.Stop+d.Place+d.Migrate+d.DestructiveUpdate+d.Canary > 0
+}
+
 // formatDryRun produces a string explaining the results of the dry run.
 func formatDryRun(resp *api.JobPlanResponse, job *api.Job) string {
 	var rolling *api.Evaluation
@@ -263,16 +330,36 @@ func formatDryRun(resp *api.JobPlanResponse, job *api.Job) string {
 			now := time.Now().In(loc)
 			out += fmt.Sprintf("[green]- If submitted now, next periodic launch would be at %s (%s from now).\n",
 				formatTime(next), formatTimeDifference(now, next, time.Second))
+			if schedule := formatPeriodicSchedule(job, resp.Diff, now); schedule != "" {
+				out += schedule + "\n"
+			}
 		}
 	}
 
+	if parameterized := formatParameterized(job, resp.Diff); parameterized != "" {
+		out += parameterized + "\n"
+	}
+
 	out = strings.TrimSuffix(out, "\n")
 	return out
 }
 
+// formatDiff produces an annotated diff of the job using the configured
+// layout.
+func formatDiff(job *api.JobDiff, verbose bool, printOpts *PrintOptions) string {
+	switch printOpts.layout() {
+	case LayoutSideBySide:
+		return formatJobDiffSideBySide(job, verbose, printOpts)
+	case LayoutFlat:
+		return formatJobDiffFlat(job)
+	default:
+		return formatJobDiff(job, verbose, printOpts)
+	}
+}
+
 // formatJobDiff produces an annotated diff of the job. If verbose mode is
 // set, added or deleted task groups and tasks are expanded.
-func formatJobDiff(job *api.JobDiff, verbose bool) string {
+func formatJobDiff(job *api.JobDiff, verbose bool, printOpts *PrintOptions) string {
 	marker, _ := getDiffString(job.Type)
 	out := fmt.Sprintf("%s[bold]Job: %q\n", marker, job.ID)
 
@@ -288,7 +375,7 @@ func formatJobDiff(job *api.JobDiff, verbose bool) string {
 	// Only show the job's field and object diffs if the job is edited or
 	// verbose mode is set.
 	if job.Type == "Edited" || verbose {
-		fo := alignedFieldAndObjects(job.Fields, job.Objects, 0, longestField, longestMarker)
+		fo := alignedFieldAndObjects(job.Fields, job.Objects, 0, longestField, longestMarker, printOpts)
 		out += fo
 		if len(fo) > 0 {
 			out += "\n"
@@ -299,7 +386,7 @@ func formatJobDiff(job *api.JobDiff, verbose bool) string {
 	for _, tg := range job.TaskGroups {
 		_, mLength := getDiffString(tg.Type)
 		kPrefix := longestMarker - mLength
-		out += fmt.Sprintf("%s\n", formatTaskGroupDiff(tg, kPrefix, verbose))
+		out += fmt.Sprintf("%s\n", formatTaskGroupDiff(tg, kPrefix, verbose, printOpts))
 	}
 
 	return out
@@ -309,43 +396,11 @@ func formatJobDiff(job *api.JobDiff, verbose bool) string {
 // verbose field is set, the task groups fields and objects are expanded even if
 // the full object is an addition or removal. tgPrefix is the number of spaces to prefix
 // the output of the task group.
-func formatTaskGroupDiff(tg *api.TaskGroupDiff, tgPrefix int, verbose bool) string {
+func formatTaskGroupDiff(tg *api.TaskGroupDiff, tgPrefix int, verbose bool, printOpts *PrintOptions) string {
 	marker, _ := getDiffString(tg.Type)
 	out := fmt.Sprintf("%s%s[bold]Task Group: %q[reset]", marker, strings.Repeat(" ", tgPrefix), tg.Name)
 
-	// Append the updates and colorize them
-	if l := len(tg.Updates); l > 0 {
-		order := make([]string, 0, l)
-		for updateType := range tg.Updates {
-			order = append(order, updateType)
-		}
-
-		sort.Strings(order)
-		updates := make([]string, 0, l)
-		for _, updateType := range order {
-			count := tg.Updates[updateType]
-			var color string
-			switch updateType {
-			case schedulerUpdateTypeIgnore:
-			case schedulerUpdateTypeCreate:
-				color = "[green]"
-			case schedulerUpdateTypeDestroy:
-				color = "[red]"
-			case schedulerUpdateTypeMigrate:
-				color = "[blue]"
-			case schedulerUpdateTypeInplaceUpdate:
-				color = "[cyan]"
-			case schedulerUpdateTypeDestructiveUpdate:
-				color = "[yellow]"
-			case schedulerUpdateTypeCanary:
-				color = "[light_yellow]"
-			}
-			updates = append(updates, fmt.Sprintf("[reset]%s%d %s", color, count, updateType))
-		}
-		out += fmt.Sprintf(" (%s[reset])\n", strings.Join(updates, ", "))
-	} else {
-		out += "[reset]\n"
-	}
+	out += formatTaskGroupUpdates(tg.Updates) + "\n"
 
 	// Determine the longest field and markers so the output is properly
 	// aligned
@@ -360,7 +415,7 @@ func formatTaskGroupDiff(tg *api.TaskGroupDiff, tgPrefix int, verbose bool) stri
 	// verbose mode is set.
 	subStartPrefix := tgPrefix + 2
 	if tg.Type == "Edited" || verbose {
-		fo := alignedFieldAndObjects(tg.Fields, tg.Objects, subStartPrefix, longestField, longestMarker)
+		fo := alignedFieldAndObjects(tg.Fields, tg.Objects, subStartPrefix, longestField, longestMarker, printOpts)
 		out += fo
 		if len(fo) > 0 {
 			out += "\n"
@@ -371,18 +426,39 @@ func formatTaskGroupDiff(tg *api.TaskGroupDiff, tgPrefix int, verbose bool) stri
 	for _, task := range tg.Tasks {
 		_, mLength := getDiffString(task.Type)
 		prefix := longestMarker - mLength
-		out += fmt.Sprintf("%s\n", formatTaskDiff(task, subStartPrefix, prefix, verbose))
+		out += fmt.Sprintf("%s\n", formatTaskDiff(task, subStartPrefix, prefix, verbose, printOpts))
 	}
 
 	return out
 }
 
+// formatTaskGroupUpdates returns the colorized summary of the updates to a
+// task group to be appended to its name.
+func formatTaskGroupUpdates(updates map[string]uint64) string {
+	if l := len(updates); l > 0 {
+		order := make([]string, 0, l)
+		for updateType := range updates {
+			order = append(order, updateType)
+		}
+
+		sort.Strings(order)
+		colored := make([]string, 0, l)
+		for _, updateType := range order {
+			count := updates[updateType]
+			color := getUpdateColor(updateType)
+			colored = append(colored, fmt.Sprintf("[reset]%s%d %s", color, count, updateType))
+		}
+		return fmt.Sprintf(" (%s[reset])", strings.Join(colored, ", "))
+	}
+	return "[reset]"
+}
+
 // formatTaskDiff produces an annotated diff of a task. If the verbose field is
 // set, the tasks fields and objects are expanded even if the full object is an
 // addition or removal. startPrefix is the number of spaces to prefix the output of
 // the task and taskPrefix is the number of spaces to put between the marker and
 // task name output.
-func formatTaskDiff(task *api.TaskDiff, startPrefix, taskPrefix int, verbose bool) string {
+func formatTaskDiff(task *api.TaskDiff, startPrefix, taskPrefix int, verbose bool, printOpts *PrintOptions) string {
 	marker, _ := getDiffString(task.Type)
 	out := fmt.Sprintf("%s%s%s[bold]Task: %q",
 		strings.Repeat(" ", startPrefix), marker, strings.Repeat(" ", taskPrefix), task.Name)
@@ -401,14 +477,14 @@ func formatTaskDiff(task *api.TaskDiff, startPrefix, taskPrefix int, verbose boo
 
 	subStartPrefix := startPrefix + 2
 	longestField, longestMarker := getLongestPrefixes(task.Fields, task.Objects)
-	out += alignedFieldAndObjects(task.Fields, task.Objects, subStartPrefix, longestField, longestMarker)
+	out += alignedFieldAndObjects(task.Fields, task.Objects, subStartPrefix, longestField, longestMarker, printOpts)
 	return out
 }
 
 // formatObjectDiff produces an annotated diff of an object. startPrefix is the
 // number of spaces to prefix the output of the object and keyPrefix is the number
 // of spaces to put between the marker and object name output.
-func formatObjectDiff(diff *api.ObjectDiff, startPrefix, keyPrefix int) string {
+func formatObjectDiff(diff *api.ObjectDiff, startPrefix, keyPrefix int, printOpts *PrintOptions) string {
 	start := strings.Repeat(" ", startPrefix)
 	marker, markerLen := getDiffString(diff.Type)
 	out := fmt.Sprintf("%s%s%s%s {\n", start, marker, strings.Repeat(" ", keyPrefix), diff.Name)
@@ -417,7 +493,7 @@ func formatObjectDiff(diff *api.ObjectDiff, startPrefix, keyPrefix int) string {
 	// properly align names and values
 	longestField, longestMarker := getLongestPrefixes(diff.Fields, diff.Objects)
 	subStartPrefix := startPrefix + keyPrefix + 2
-	out += alignedFieldAndObjects(diff.Fields, diff.Objects, subStartPrefix, longestField, longestMarker)
+	out += alignedFieldAndObjects(diff.Fields, diff.Objects, subStartPrefix, longestField, longestMarker, printOpts)
 
 	endprefix := strings.Repeat(" ", startPrefix+markerLen+keyPrefix)
 	return fmt.Sprintf("%s\n%s}", out, endprefix)
@@ -427,7 +503,7 @@ func formatObjectDiff(diff *api.ObjectDiff, startPrefix, keyPrefix int) string {
 // number of spaces to prefix the output of the field, keyPrefix is the number
 // of spaces to put between the marker and field name output and valuePrefix is
 // the number of spaces to put infront of the value for aligning values.
-func formatFieldDiff(diff *api.FieldDiff, startPrefix, keyPrefix, valuePrefix int) string {
+func formatFieldDiff(diff *api.FieldDiff, startPrefix, keyPrefix, valuePrefix int, printOpts *PrintOptions) string {
 	marker, _ := getDiffString(diff.Type)
 	out := fmt.Sprintf("%s%s%s%s: %s",
 		strings.Repeat(" ", startPrefix),
@@ -435,15 +511,19 @@ func formatFieldDiff(diff *api.FieldDiff, startPrefix, keyPrefix, valuePrefix in
 		diff.Name,
 		strings.Repeat(" ", valuePrefix))
 
+	// Fit the value in the remaining width, continuing wrapped lines at the
+	// start of the value.
+	_, markerLen := getDiffString(diff.Type)
+	valueStart := startPrefix + markerLen + keyPrefix + displayWidth(diff.Name) + 2 + valuePrefix
 	switch diff.Type {
 	case "Added":
-		out += fmt.Sprintf("%q", diff.New)
+		out += fitValue(fmt.Sprintf("%q", diff.New), valueStart, printOpts)
 	case "Deleted":
-		out += fmt.Sprintf("%q", diff.Old)
+		out += fitValue(fmt.Sprintf("%q", diff.Old), valueStart, printOpts)
 	case "Edited":
-		out += fmt.Sprintf("%q => %q", diff.Old, diff.New)
+		out += fitEdit(fmt.Sprintf("%q", diff.Old), fmt.Sprintf("%q", diff.New), valueStart, printOpts)
 	default:
-		out += fmt.Sprintf("%q", diff.New)
+		out += fitValue(fmt.Sprintf("%q", diff.New), valueStart, printOpts)
 	}
 
 	// Color the annotations where possible
@@ -457,43 +537,60 @@ func formatFieldDiff(diff *api.FieldDiff, startPrefix, keyPrefix, valuePrefix in
 // alignedFieldAndObjects is a helper method that prints fields and objects
 // properly aligned.
 func alignedFieldAndObjects(fields []*api.FieldDiff, objects []*api.ObjectDiff,
-	startPrefix, longestField, longestMarker int) string {
+	startPrefix, longestField, longestMarker int, printOpts *PrintOptions) string {
+
+	var lines []string
+	walkAlignedFieldsAndObjects(fields, objects, longestField, longestMarker,
+		func(field *api.FieldDiff, keyPrefix, valuePrefix int) {
+			lines = append(lines, formatFieldDiff(field, startPrefix, keyPrefix, valuePrefix, printOpts))
+		},
+		func(object *api.ObjectDiff, keyPrefix int) {
+			lines = append(lines, formatObjectDiff(object, startPrefix, keyPrefix, printOpts))
+		})
+	return strings.Join(lines, "\n")
+}
 
-	var out string
-	numFields := len(fields)
-	numObjects := len(objects)
-	haveObjects := numObjects != 0
-	for i, field := range fields {
-		_, mLength := getDiffString(field.Type)
-		kPrefix := longestMarker - mLength
-		vPrefix := longestField - len(field.Name)
-		out += formatFieldDiff(field, startPrefix, kPrefix, vPrefix)
+// walkAlignedFieldsAndObjects calls field for every field and then object for
+// every object with the number of spaces to put after their marker, and after
+// the name of fields, to align them.
+func walkAlignedFieldsAndObjects(fields []*api.FieldDiff, objects []*api.ObjectDiff, longestField, longestMarker int,
+	field func(field *api.FieldDiff, keyPrefix, valuePrefix int), object func(object *api.ObjectDiff, keyPrefix int)) {
 
-		// Avoid a dangling new line
-		if i+1 != numFields || haveObjects {
-			out += "\n"
-		}
+	for _, f := range fields {
+		_, mLength := getDiffString(f.Type)
+		field(f, longestMarker-mLength, longestField-displayWidth(f.Name))
 	}
-
-	for i, object := range objects {
-		_, mLength := getDiffString(object.Type)
-		kPrefix := longestMarker - mLength
-		out += formatObjectDiff(object, startPrefix, kPrefix)
-
-		// Avoid a dangling new line
-		if i+1 != numObjects {
-			out += "\n"
-		}
+	for _, o := range objects {
+		_, mLength := getDiffString(o.Type)
+		object(o, longestMarker-mLength)
 	}
+}
 
-	return out
+// getUpdateColor returns the color used to display a task group update type.
+func getUpdateColor(updateType string) string {
+	switch updateType {
+	case schedulerUpdateTypeCreate:
+		return "[green]"
+	case schedulerUpdateTypeDestroy:
+		return "[red]"
+	case schedulerUpdateTypeMigrate:
+		return "[blue]"
+	case schedulerUpdateTypeInplaceUpdate:
+		return "[cyan]"
+	case schedulerUpdateTypeDestructiveUpdate:
+		return "[yellow]"
+	case schedulerUpdateTypeCanary:
+		return "[light_yellow]"
+	default:
+		return ""
+	}
 }
 
 // getLongestPrefixes takes a list  of fields and objects and determines the
 // longest field name and the longest marker.
 func getLongestPrefixes(fields []*api.FieldDiff, objects []*api.ObjectDiff) (longestField, longestMarker int) {
 	for _, field := range fields {
-		if l := len(field.Name); l > longestField {
+		if l := displayWidth(field.Name); l > longestField {
 			longestField = l
 		}
 		if _, l := getDiffString(field.Type); l > longestMarker {
@@ -533,19 +630,29 @@ func colorAnnotations(annotations []string) string {
 
 	colored := make([]string, l)
 	for i, annotation := range annotations {
-		switch annotation {
-		case "forces create":
-			colored[i] = fmt.Sprintf("[green]%s[reset]", annotation)
-		case "forces destroy":
-			colored[i] = fmt.Sprintf("[red]%s[reset]", annotation)
-		case "forces in-place update":
-			colored[i] = fmt.Sprintf("[cyan]%s[reset]", annotation)
-		case "forces create/destroy update":
-			colored[i] = fmt.Sprintf("[yellow]%s[reset]", annotation)
-		default:
+		if color := getAnnotationColor(annotation); color != "" {
+			colored[i] = fmt.Sprintf("%s%s[reset]", color, annotation)
+		} else {
 			colored[i] = annotation
 		}
 	}
 
 	return strings.Join(colored, ", ")
 }
+
+// getAnnotationColor returns the color used to display an annotation or an
+// empty string if the annotation is not colored.
+func getAnnotationColor(annotation string) string {
+	switch annotation {
+	case "forces create":
+		return "[green]"
+	case "forces destroy":
+		return "[red]"
+	case "forces in-place update":
+		return "[cyan]"
+	case "forces create/destroy update":
+		return "[yellow]"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,99 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// planRequest is the namespace and region a plan was submitted to.
+type planRequest struct {
+	namespace, region string
+}
+
+// planHandler records the namespace and region of every plan by job ID and
+// responds with an empty diff.
+func planHandler(mu *sync.Mutex, requests map[string]planRequest) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/v1/job/"), "/plan")
+		mu.Lock()
+		requests[id] = planRequest{
+			namespace: r.URL.Query().Get("namespace"),
+			region:    r.URL.Query().Get("region"),
+		}
+		mu.Unlock()
+
+		json.NewEncoder(w).Encode(&api.JobPlanResponse{
+			Diff:        &api.JobDiff{Type: diffTypeNone, ID: id},
+			Annotations: &api.PlanAnnotations{},
+		})
+	})
+}
+
+func TestPlanAndPrintDiffConcurrentNamespaces(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]planRequest)
+	client := testClient(t, planHandler(&mu, requests))
+	client.SetNamespace("default")
+	client.SetRegion("global")
+
+	const n = 16
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		job := testJob(fmt.Sprintf("job-%d", i), fmt.Sprintf("ns-%d", i))
+		region := fmt.Sprintf("region-%d", i%2)
+		job.Region = &region
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := PlanAndPrintDiffContext(context.Background(), client, job, nil, nil, ioutil.Discard); err != nil {
+				t.Errorf("error planning job %q: %v", *job.ID, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("job-%d", i)
+		expected := planRequest{namespace: fmt.Sprintf("ns-%d", i), region: fmt.Sprintf("region-%d", i%2)}
+		if got := requests[id]; got != expected {
+			t.Errorf("job %q was planned in %+v, expected %+v", id, got, expected)
+		}
+	}
+
+	// Requests without options still use the namespace and region of the
+	// client.
+	job := testJob("unscoped", "")
+	job.Region = nil
+	if _, _, err := client.Jobs().PlanOpts(job, &api.PlanOptions{Diff: true}, nil); err != nil {
+		t.Fatalf("error planning job: %v", err)
+	}
+	if got, expected := requests["unscoped"], (planRequest{namespace: "default", region: "global"}); got != expected {
+		t.Errorf("the client config was modified: got %+v, expected %+v", got, expected)
+	}
+}
+
+func TestWriteOptions(t *testing.T) {
+	job := testJob("example", "web")
+	region := "eu"
+	job.Region = &region
+
+	w := writeOptions(context.Background(), job)
+	if w.Namespace != "web" || w.Region != "eu" {
+		t.Errorf("unexpected write options: namespace %q, region %q", w.Namespace, w.Region)
+	}
+
+	job.Namespace, job.Region = nil, nil
+	w = writeOptions(context.Background(), job)
+	if w.Namespace != "" || w.Region != "" {
+		t.Errorf("unexpected write options: namespace %q, region %q", w.Namespace, w.Region)
+	}
+}
@@ -0,0 +1,216 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Payload modes of a parameterized job.
+const (
+	dispatchPayloadOptional  = "optional"
+	dispatchPayloadRequired  = "required"
+	dispatchPayloadForbidden = "forbidden"
+)
+
+const (
+	// dispatchPayloadSizeLimit is the maximum size of a dispatch payload
+	// accepted by Nomad.
+	dispatchPayloadSizeLimit = 16 * 1024
+)
+
+// DispatchPreview is the result of previewing a dispatch of a parameterized
+// job.
+type DispatchPreview struct {
+	// Meta is the meta of the dispatched job: the meta of the job overridden
+	// by the meta of the dispatch.
+	Meta map[string]string
+
+	// Errors are the reasons Nomad would reject the dispatch.
+	Errors []string
+}
+
+// PreviewDispatch checks a dispatch of the parameterized job with the given
+// meta and payload against the job's parameterized config, without contacting
+// a Nomad server, and prints the result. Pass the job being planned to check
+// existing dispatchers against its new requirements.
+func PreviewDispatch(job *api.Job, meta map[string]string, payload []byte, output io.Writer) (*DispatchPreview, error) {
+	if !job.IsParameterized() {
+		return nil, fmt.Errorf("job %q is not parameterized", stringValue(job.ID))
+	}
+	preview := previewDispatch(job.ParameterizedJob, job.Meta, meta, payload)
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	print(colorize().Color(fmt.Sprintf("[bold]Dispatch preview of job %q:[reset]", stringValue(job.ID))))
+	print(colorize().Color(formatDispatchPreview(preview)))
+	print("")
+	return preview, nil
+}
+
+// previewDispatch validates a dispatch the same way Nomad does.
+func previewDispatch(config *api.ParameterizedJobConfig, jobMeta, meta map[string]string, payload []byte) *DispatchPreview {
+	preview := &DispatchPreview{Meta: make(map[string]string, len(jobMeta)+len(meta))}
+	for k, v := range jobMeta {
+		preview.Meta[k] = v
+	}
+	for k, v := range meta {
+		preview.Meta[k] = v
+	}
+
+	switch config.Payload {
+	case dispatchPayloadForbidden:
+		if len(payload) > 0 {
+			preview.Errors = append(preview.Errors, "Payload is not allowed")
+		}
+	case dispatchPayloadRequired:
+		if len(payload) == 0 {
+			preview.Errors = append(preview.Errors, "Payload is not provided but required")
+		}
+	}
+	if len(payload) > dispatchPayloadSizeLimit {
+		preview.Errors = append(preview.Errors, fmt.Sprintf(
+			"Payload exceeds maximum size; %d > %d", len(payload), dispatchPayloadSizeLimit))
+	}
+
+	allowed := make(map[string]bool)
+	for _, k := range config.MetaRequired {
+		allowed[k] = true
+	}
+	for _, k := range config.MetaOptional {
+		allowed[k] = true
+	}
+	var unpermitted []string
+	for k := range meta {
+		if !allowed[k] {
+			unpermitted = append(unpermitted, k)
+		}
+	}
+	if len(unpermitted) > 0 {
+		sort.Strings(unpermitted)
+		preview.Errors = append(preview.Errors, fmt.Sprintf(
+			"Dispatch request included unpermitted metadata keys: %v", unpermitted))
+	}
+
+	var missing []string
+	for _, k := range config.MetaRequired {
+		if _, ok := meta[k]; !ok {
+			missing = append(missing, k)
+		}
+	}
+	if len(missing) > 0 {
+		preview.Errors = append(preview.Errors, fmt.Sprintf(
+			"Dispatch did not provide required meta keys: %v", missing))
+	}
+	return preview
+}
+
+// formatDispatchPreview produces a string explaining whether a dispatch would
+// be accepted and the meta of the dispatched job.
+func formatDispatchPreview(preview *DispatchPreview) string {
+	var out string
+	if len(preview.Errors) == 0 {
+		out += "[bold][green]- Dispatch would be accepted.[reset]\n"
+	} else {
+		out += "[bold][red]- Dispatch would be rejected:[reset]\n"
+		for _, e := range preview.Errors {
+			out += fmt.Sprintf("[red]  * %s[reset]\n", e)
+		}
+	}
+
+	if len(preview.Meta) > 0 {
+		keys := make([]string, 0, len(preview.Meta))
+		for k := range preview.Meta {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		kv := make([]string, len(keys))
+		for i, k := range keys {
+			kv[i] = fmt.Sprintf("%s|%s", k, preview.Meta[k])
+		}
+		out += "  Dispatched job meta:\n"
+		out += indent(formatKV(kv), "    ") + "\n"
+	}
+	return strings.TrimSuffix(out, "\n")
+}
+
+// formatParameterized produces a string describing how the parameterized job
+// is dispatched and warning about changes to its requirements that may break
+// existing dispatchers.
+func formatParameterized(job *api.Job, diff *api.JobDiff) string {
+	var out string
+	if job.IsParameterized() {
+		config := job.ParameterizedJob
+		payload := config.Payload
+		if payload == "" {
+			payload = dispatchPayloadOptional
+		}
+		out += "[green]- Parameterized job: it only runs when dispatched.[reset]\n"
+		out += indent(formatKV([]string{
+			fmt.Sprintf("Payload|%s", payload),
+			fmt.Sprintf("Meta Required|%s", strings.Join(config.MetaRequired, ", ")),
+			fmt.Sprintf("Meta Optional|%s", strings.Join(config.MetaOptional, ", ")),
+		}), "    ") + "\n"
+	}
+
+	for _, warning := range parameterizedChangeWarnings(job, diff) {
+		out += fmt.Sprintf("[yellow]- WARNING: %s[reset]\n", warning)
+	}
+	return strings.TrimSuffix(out, "\n")
+}
+
+// parameterizedChangeWarnings returns warnings about changes to the
+// parameterized config of the job that would reject dispatches accepted by
+// the registered job.
+func parameterizedChangeWarnings(job *api.Job, diff *api.JobDiff) []string {
+	if diff == nil {
+		return nil
+	}
+	for _, object := range diff.Objects {
+		if object.Name != "ParameterizedJob" {
+			continue
+		}
+		switch object.Type {
+		case diffTypeAdded:
+			return []string{"The job becomes parameterized: it will no longer run when submitted and must be dispatched."}
+		case diffTypeDeleted:
+			return []string{"The job is no longer parameterized: existing dispatchers will break."}
+		}
+	}
+
+	if !job.IsParameterized() {
+		return nil
+	}
+	config := job.ParameterizedJob
+	allowed := make(map[string]bool)
+	for _, k := range config.MetaRequired {
+		allowed[k] = true
+	}
+	for _, k := range config.MetaOptional {
+		allowed[k] = true
+	}
+
+	var warnings []string
+	for _, c := range QueryDiff(diff).Match("ParameterizedJob.**") {
+		switch {
+		case strings.Contains(c.Path, "MetaRequired") && c.Type == diffTypeAdded:
+			warnings = append(warnings, fmt.Sprintf(
+				"Meta key %q is now required: existing dispatchers that don't set it will be rejected.", c.New))
+		case strings.Contains(c.Path, "Meta") && c.Type == diffTypeDeleted && !allowed[c.Old]:
+			warnings = append(warnings, fmt.Sprintf(
+				"Meta key %q is no longer permitted: existing dispatchers that set it will be rejected.", c.Old))
+		case strings.HasSuffix(c.Path, ".Payload") && c.New == dispatchPayloadRequired:
+			warnings = append(warnings,
+				"A payload is now required: existing dispatchers that don't send one will be rejected.")
+		case strings.HasSuffix(c.Path, ".Payload") && c.New == dispatchPayloadForbidden:
+			warnings = append(warnings,
+				"A payload is now forbidden: existing dispatchers that send one will be rejected.")
+		}
+	}
+	sort.Strings(warnings)
+	return warnings
+}
@@ -0,0 +1,9 @@
+// Package nomaddiffprinter plans Nomad jobs and prints their diffs the same
+// way as `nomad job plan`, along with previews of the history, drift, revert,
+// scale, stop and dispatch of a job.
+//
+// It is a library only: there is no command line interface. Functions such as
+// PrintJobHistory, PlanScaleAndPrintDiff and PlanAll are meant to be wired
+// into the callers' own commands, which own flag parsing, the client
+// configuration and the process exit code.
+package nomaddiffprinter
@@ -0,0 +1,89 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// DetectDriftAndPrintDiff compares the given job with the version currently
+// registered in the cluster and prints the difference. Unlike
+// PlanAndPrintDiff no plan is submitted: the diff is computed locally from the
+// result of Jobs().Info. The returned exit code is 0 when the cluster matches
+// the job and 1 when it has drifted.
+func DetectDriftAndPrintDiff(client *api.Client, job *api.Job, output io.Writer) (diff *api.JobDiff, exitCode int, err error) {
+	return DetectDriftAndPrintDiffContext(context.Background(), client, job, output)
+}
+
+// DetectDriftAndPrintDiffContext is like DetectDriftAndPrintDiff but the
+// lookup of the registered job is bound to ctx.
+func DetectDriftAndPrintDiffContext(ctx context.Context, client *api.Client, job *api.Job, output io.Writer) (diff *api.JobDiff, exitCode int, err error) {
+	if job.ID == nil {
+		return nil, 0, fmt.Errorf("job must have an ID")
+	}
+
+	q := &api.QueryOptions{}
+	if r := job.Region; r != nil {
+		q.Region = *r
+	}
+	if n := job.Namespace; n != nil {
+		q.Namespace = *n
+	}
+
+	registered, _, err := client.Jobs().Info(*job.ID, q.WithContext(ctx))
+	if err != nil && !isNotFound(err) {
+		return nil, 0, contextError(ctx, "job info", err)
+	}
+
+	diff, err = DiffJobs(registered, job)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	return diff, outputDrift(registered, diff, print), nil
+}
+
+// outputDrift prints the drift diff and returns the exit code.
+func outputDrift(registered *api.Job, diff *api.JobDiff, print func(string)) int {
+	if registered == nil {
+		print(colorize().Color(fmt.Sprintf(
+			"[bold][yellow]Job %q is not registered in the cluster.[reset]\n", diff.ID)))
+	} else {
+		print(colorize().Color(fmt.Sprintf("[bold]Registered version:[reset] %s\n",
+			formatJobVersion(registered))))
+	}
+
+	if diff.Type == diffTypeNone {
+		print(colorize().Color("[bold][green]No drift detected.[reset]"))
+		return 0
+	}
+
+	print(fmt.Sprintf("%s\n",
+		colorize().Color(strings.TrimSpace(formatJobDiff(diff, true, nil)))))
+	print(colorize().Color(fmt.Sprintf(
+		"[bold][yellow]Job %q has drifted from its specification.[reset]", diff.ID)))
+	return 1
+}
+
+// formatJobVersion describes a registered version of a job.
+func formatJobVersion(job *api.Job) string {
+	out := fmt.Sprintf("%d", jobVersion(job))
+	if job.SubmitTime != nil {
+		out += fmt.Sprintf(" (submitted %s)", formatTime(time.Unix(0, *job.SubmitTime)))
+	}
+	return out
+}
+
+// isNotFound returns whether the error is a 404 returned by the Nomad API.
+func isNotFound(err error) bool {
+	code, _, ok := parseResponseError(err)
+	return ok && code == http.StatusNotFound
+}
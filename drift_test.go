@@ -0,0 +1,40 @@
+package nomaddiffprinter
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+)
+
+func TestIsNotFound(t *testing.T) {
+	cases := map[string]bool{
+		"Unexpected response code: 404 (job not found)":                    true,
+		"Unexpected response code: 500 (rpc error: dial 10.0.0.1:4040)":    false,
+		"Get \"http://127.0.0.1:4040/v1/job/example\": connection refused": false,
+		"job 404 not found": false,
+	}
+	for msg, expected := range cases {
+		if got := isNotFound(errors.New(msg)); got != expected {
+			t.Errorf("isNotFound(%q) = %v, expected %v", msg, got, expected)
+		}
+	}
+}
+
+func TestDetectDriftUnregisteredJob(t *testing.T) {
+	client := testClient(t, http.NotFoundHandler())
+	diff, exitCode, err := DetectDriftAndPrintDiff(client, testJob("example", "default"), ioutil.Discard)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff.Type != diffTypeAdded || exitCode != 1 {
+		t.Errorf("expected an added job and exit code 1, got %s and %d", diff.Type, exitCode)
+	}
+
+	client = testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rpc error: dial 10.0.0.1:4040", http.StatusInternalServerError)
+	}))
+	if _, _, err := DetectDriftAndPrintDiff(client, testJob("example", "default"), ioutil.Discard); err == nil {
+		t.Errorf("expected an error when the job can't be read")
+	}
+}
@@ -0,0 +1,173 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// PermissionDeniedError is returned when the ACL token used by the client is
+// not allowed to plan the job.
+type PermissionDeniedError struct {
+	JobID   string
+	Message string
+}
+
+func (e *PermissionDeniedError) Error() string {
+	return fmt.Sprintf("permission denied planning job %q: %s", e.JobID, e.Message)
+}
+
+// ValidationError is returned when the server rejects the job as invalid.
+type ValidationError struct {
+	JobID   string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("job %q failed validation: %s", e.JobID, e.Message)
+}
+
+// PolicyError is returned when the job fails a Sentinel policy. A failed
+// soft-mandatory policy can be overridden, a hard-mandatory one cannot.
+type PolicyError struct {
+	JobID         string
+	Message       string
+	HardMandatory bool
+
+	// Policies are the results of the policies parsed from the message.
+	Policies []*PolicyResult
+}
+
+func (e *PolicyError) Error() string {
+	level := "soft-mandatory"
+	if e.HardMandatory {
+		level = "hard-mandatory"
+	}
+	return fmt.Sprintf("job %q failed %s Sentinel policy: %s", e.JobID, level, e.Message)
+}
+
+// ConnectionError is returned when the Nomad server could not be reached or
+// did not respond in time.
+type ConnectionError struct {
+	JobID   string
+	Message string
+	Err     error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("error connecting to Nomad to plan job %q: %s", e.JobID, e.Message)
+}
+
+// Unwrap returns the underlying network error.
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// Timeout returns whether the connection timed out.
+func (e *ConnectionError) Timeout() bool {
+	var netErr net.Error
+	return errors.As(e.Err, &netErr) && netErr.Timeout()
+}
+
+// UnknownRegionError is returned when the region of the job is not known to
+// the server.
+type UnknownRegionError struct {
+	JobID   string
+	Region  string
+	Message string
+}
+
+func (e *UnknownRegionError) Error() string {
+	return fmt.Sprintf("unknown region %q for job %q: %s", e.Region, e.JobID, e.Message)
+}
+
+// UnknownNamespaceError is returned when the namespace of the job does not
+// exist.
+type UnknownNamespaceError struct {
+	JobID     string
+	Namespace string
+	Message   string
+}
+
+func (e *UnknownNamespaceError) Error() string {
+	return fmt.Sprintf("unknown namespace %q for job %q: %s", e.Namespace, e.JobID, e.Message)
+}
+
+// planError classifies an error returned by a plan of the job into one of
+// the typed errors above, or a ContextError if ctx is done. The api package
+// reports every failed request as a formatted string, so the classification
+// is based on its status code and the message of the server. Any other 400
+// response or list of errors is reported as a ValidationError. Errors that
+// can't be classified are returned unchanged.
+func planError(ctx context.Context, job *api.Job, err error) error {
+	if ctx.Err() != nil {
+		return contextError(ctx, "plan", err)
+	}
+	jobID := stringValue(job.ID)
+
+	code, msg, ok := parseResponseError(err)
+	if !ok {
+		var netErr net.Error
+		if errors.As(err, &netErr) {
+			return &ConnectionError{JobID: jobID, Message: netErr.Error(), Err: err}
+		}
+		return err
+	}
+
+	lower := strings.ToLower(msg)
+	policies := parsePolicyResults(msg)
+	var failed, hard bool
+	for _, p := range policies {
+		failed = failed || !p.Passed
+		hard = hard || (!p.Passed && !p.SoftMandatory)
+	}
+	switch {
+	case code == http.StatusForbidden || strings.Contains(lower, "permission denied"):
+		return &PermissionDeniedError{JobID: jobID, Message: msg}
+	case failed:
+		return &PolicyError{JobID: jobID, Message: msg, HardMandatory: hard, Policies: policies}
+	case strings.Contains(lower, "hard-mandatory"):
+		return &PolicyError{JobID: jobID, Message: msg, HardMandatory: true}
+	case strings.Contains(lower, "soft-mandatory"):
+		return &PolicyError{JobID: jobID, Message: msg}
+	case strings.Contains(lower, "no path to region"):
+		return &UnknownRegionError{JobID: jobID, Region: stringValue(job.Region), Message: msg}
+	case strings.Contains(lower, "namespace") &&
+		(strings.Contains(lower, "not found") || strings.Contains(lower, "does not exist") || strings.Contains(lower, "nonexistent")):
+		return &UnknownNamespaceError{JobID: jobID, Namespace: stringValue(job.Namespace), Message: msg}
+	case code == http.StatusBadRequest ||
+		strings.Contains(lower, "validation failed") ||
+		strings.Contains(lower, "error occurred:") || strings.Contains(lower, "errors occurred:"):
+		return &ValidationError{JobID: jobID, Message: msg}
+	}
+	return err
+}
+
+// parseResponseError extracts the status code and server message from an
+// error of the form "Unexpected response code: 403 (Permission denied)".
+func parseResponseError(err error) (code int, msg string, ok bool) {
+	const prefix = "Unexpected response code: "
+	s := err.Error()
+	if !strings.HasPrefix(s, prefix) {
+		return 0, "", false
+	}
+	s = strings.TrimPrefix(s, prefix)
+
+	end := strings.IndexByte(s, ' ')
+	if end < 0 {
+		end = len(s)
+	}
+	code, convErr := strconv.Atoi(s[:end])
+	if convErr != nil {
+		return 0, "", false
+	}
+	msg = strings.TrimSpace(s[end:])
+	msg = strings.TrimSuffix(strings.TrimPrefix(msg, "("), ")")
+	return code, strings.TrimSpace(msg), true
+}
@@ -0,0 +1,124 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"testing"
+)
+
+func TestPlanError(t *testing.T) {
+	cases := []struct {
+		name     string
+		err      error
+		expected string
+	}{
+		{
+			name:     "permission denied",
+			err:      errors.New("Unexpected response code: 403 (Permission denied)"),
+			expected: "*nomaddiffprinter.PermissionDeniedError",
+		},
+		{
+			name:     "validation",
+			err:      errors.New("Unexpected response code: 400 (1 error occurred:\n\t* Missing job datacenters\n\n)"),
+			expected: "*nomaddiffprinter.ValidationError",
+		},
+		{
+			name:     "unknown region",
+			err:      errors.New("Unexpected response code: 500 (No path to region)"),
+			expected: "*nomaddiffprinter.UnknownRegionError",
+		},
+		{
+			name:     "unknown namespace",
+			err:      errors.New(`Unexpected response code: 500 (job "example" is in nonexistent namespace "web")`),
+			expected: "*nomaddiffprinter.UnknownNamespaceError",
+		},
+		{
+			name: "connection refused",
+			err: &url.Error{
+				Op:  "Put",
+				URL: "http://127.0.0.1:4646/v1/job/example/plan",
+				Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+			},
+			expected: "*nomaddiffprinter.ConnectionError",
+		},
+		{
+			// Any other multierror is reported as a validation error.
+			name:     "multierror",
+			err:      errors.New("Unexpected response code: 500 (1 error occurred:\n\t* rpc error: eval broker disabled\n\n)"),
+			expected: "*nomaddiffprinter.ValidationError",
+		},
+		{
+			name:     "other response",
+			err:      errors.New("Unexpected response code: 500 (rpc error: No cluster leader)"),
+			expected: "*errors.errorString",
+		},
+		{
+			name:     "other error",
+			err:      errors.New("failed to encode job"),
+			expected: "*errors.errorString",
+		},
+	}
+
+	job := testJob("example", "web")
+	region := "eu"
+	job.Region = &region
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := planError(context.Background(), job, c.err)
+			if got := fmt.Sprintf("%T", err); got != c.expected {
+				t.Errorf("expected a %s, got %s: %v", c.expected, got, err)
+			}
+		})
+	}
+}
+
+func TestPlanErrorFields(t *testing.T) {
+	job := testJob("example", "web")
+	region := "eu"
+	job.Region = &region
+
+	err := planError(context.Background(), job, errors.New("Unexpected response code: 403 (Permission denied)"))
+	var permErr *PermissionDeniedError
+	if !errors.As(err, &permErr) || permErr.JobID != "example" || permErr.Message != "Permission denied" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	err = planError(context.Background(), job, errors.New("Unexpected response code: 500 (No path to region)"))
+	var regionErr *UnknownRegionError
+	if !errors.As(err, &regionErr) || regionErr.Region != "eu" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	err = planError(context.Background(), job, errors.New(`Unexpected response code: 500 (job "example" is in nonexistent namespace "web")`))
+	var nsErr *UnknownNamespaceError
+	if !errors.As(err, &nsErr) || nsErr.Namespace != "web" {
+		t.Errorf("unexpected error: %#v", err)
+	}
+
+	err = planError(context.Background(), job, &url.Error{
+		Op:  "Put",
+		URL: "http://127.0.0.1:4646/v1/job/example/plan",
+		Err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")},
+	})
+	var connErr *ConnectionError
+	if !errors.As(err, &connErr) || connErr.Timeout() {
+		t.Errorf("unexpected error: %#v", err)
+	}
+	var opErr *net.OpError
+	if !errors.As(err, &opErr) {
+		t.Errorf("expected the error to wrap the network error")
+	}
+}
+
+func TestParseResponseError(t *testing.T) {
+	code, msg, ok := parseResponseError(errors.New("Unexpected response code: 400 (1 error occurred:\n\t* Missing job datacenters\n\n)"))
+	if !ok || code != 400 || msg != "1 error occurred:\n\t* Missing job datacenters" {
+		t.Errorf("unexpected result: %d, %q, %v", code, msg, ok)
+	}
+	if _, _, ok := parseResponseError(errors.New("connection refused")); ok {
+		t.Errorf("expected no response code")
+	}
+}
@@ -0,0 +1,109 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// evalTriggerDescriptions explain what a follow-up evaluation created by a
+// plan is for, keyed by its TriggeredBy.
+var evalTriggerDescriptions = map[string]string{
+	"rolling-update":     "Places the next batch of the rolling update once the current batch is healthy.",
+	"deployment-watcher": "Continues the deployment once its state changes, such as when canaries are promoted.",
+	"failed-follow-up":   "Retries the evaluation after the delay because it could not be completed.",
+	"retry-failed-alloc": "Reschedules failed allocations once their reschedule delay has passed.",
+	"queued-allocs":      "Places the allocations that could not be placed once resources become available.",
+	"max-plan-attempts":  "Retries scheduling because the plan could not be applied after the maximum attempts.",
+	"preemption":         "Reschedules the allocations that would be preempted.",
+	"alloc-stop":         "Replaces allocations that would be stopped.",
+	"job-scaling":        "Applies a change to the count of a task group.",
+}
+
+// formatCreatedEvals produces a string listing the evaluations the plan would
+// create, with what triggers them, when they run and what they mean.
+func formatCreatedEvals(evals []*api.Evaluation) string {
+	out := "[bold]Follow-up evaluations:[reset]\n"
+	if len(evals) == 0 {
+		return out + "- No follow-up evaluations would be created."
+	}
+
+	now := time.Now()
+	for _, eval := range evals {
+		out += fmt.Sprintf("- [bold]%s[reset]", eval.TriggeredBy)
+		if eval.ID != "" {
+			out += fmt.Sprintf(" (%s)", limit(eval.ID, 8))
+		}
+		out += "\n"
+
+		var kv []string
+		if eval.Status != "" {
+			status := eval.Status
+			if eval.StatusDescription != "" {
+				status += ": " + eval.StatusDescription
+			}
+			kv = append(kv, fmt.Sprintf("Status|%s", status))
+		}
+		if eval.Wait > 0 {
+			kv = append(kv, fmt.Sprintf("Wait|%s", eval.Wait))
+		}
+		if !eval.WaitUntil.IsZero() {
+			kv = append(kv, fmt.Sprintf("Wait Until|%s (%s from now)",
+				formatTime(eval.WaitUntil), formatTimeDifference(now, eval.WaitUntil, time.Second)))
+		}
+		if eval.BlockedEval != "" {
+			kv = append(kv, fmt.Sprintf("Blocked Eval|%s", limit(eval.BlockedEval, 8)))
+		}
+		if queued := formatQueuedAllocations(eval.QueuedAllocations); queued != "" {
+			kv = append(kv, fmt.Sprintf("Queued Allocations|%s", queued))
+		}
+		if len(kv) > 0 {
+			out += indent(formatKV(kv), "  ") + "\n"
+		}
+
+		if desc := evalDescription(eval); desc != "" {
+			out += fmt.Sprintf("  %s\n", desc)
+		}
+	}
+	return strings.TrimSuffix(out, "\n")
+}
+
+// evalDescription explains what a follow-up evaluation means.
+func evalDescription(eval *api.Evaluation) string {
+	if eval.Status == "blocked" {
+		return "Blocked until resources become available for the allocations that could not be placed."
+	}
+	if desc, ok := evalTriggerDescriptions[eval.TriggeredBy]; ok {
+		return desc
+	}
+	return ""
+}
+
+// formatQueuedAllocations formats the number of queued allocations per task
+// group, omitting groups with none queued.
+func formatQueuedAllocations(queued map[string]int) string {
+	var groups []string
+	for tg, n := range queued {
+		if n > 0 {
+			groups = append(groups, tg)
+		}
+	}
+	sort.Strings(groups)
+
+	parts := make([]string, len(groups))
+	for i, tg := range groups {
+		parts[i] = fmt.Sprintf("%s=%d", tg, queued[tg])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// limit truncates s to at most n characters.
+func limit(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// Change is a changed leaf of a job diff addressed by its fully qualified
+// path, such as TaskGroup[web].Task[app].Config.image.
+type Change struct {
+	// Path is the fully qualified path of the changed field.
+	Path string
+
+	// Type is the diff type of the change: Added, Deleted or Edited.
+	Type string
+
+	// Old and New are the values of the field before and after the change.
+	// They are empty for task groups, tasks and objects.
+	Old, New string
+
+	// Annotations are the annotations attached to the change, such as
+	// "forces create/destroy update".
+	Annotations []string
+}
+
+// flattenJobDiff walks the job diff and returns every changed field. Task
+// groups, tasks and objects without any changed fields, such as an added
+// task in a non-verbose diff, are returned as a change of their own, as are
+// tasks with annotations such as "forces create/destroy update".
+func flattenJobDiff(job *api.JobDiff) []*Change {
+	var changes []*Change
+	changes = appendFlatFieldsAndObjects(changes, "", job.Fields, job.Objects)
+
+	for _, tg := range job.TaskGroups {
+		tgPath := fmt.Sprintf("TaskGroup[%s]", tg.Name)
+		n := len(changes)
+		changes = appendFlatFieldsAndObjects(changes, tgPath, tg.Fields, tg.Objects)
+
+		for _, task := range tg.Tasks {
+			taskPath := fmt.Sprintf("%s.Task[%s]", tgPath, task.Name)
+			m := len(changes)
+			changes = appendFlatFieldsAndObjects(changes, taskPath, task.Fields, task.Objects)
+			if (len(changes) == m && task.Type != diffTypeNone) || len(task.Annotations) != 0 {
+				changes = append(changes, &Change{Path: taskPath, Type: task.Type, Annotations: task.Annotations})
+			}
+		}
+		if len(changes) == n && tg.Type != diffTypeNone {
+			changes = append(changes, &Change{Path: tgPath, Type: tg.Type})
+		}
+	}
+	return changes
+}
+
+func appendFlatFieldsAndObjects(changes []*Change, prefix string, fields []*api.FieldDiff, objects []*api.ObjectDiff) []*Change {
+	for _, field := range fields {
+		changes = append(changes, &Change{
+			Path:        joinPath(prefix, field.Name),
+			Type:        field.Type,
+			Old:         field.Old,
+			New:         field.New,
+			Annotations: field.Annotations,
+		})
+	}
+	for _, object := range objects {
+		path := joinPath(prefix, object.Name)
+		n := len(changes)
+		changes = appendFlatFieldsAndObjects(changes, path, object.Fields, object.Objects)
+		if len(changes) == n && object.Type != diffTypeNone {
+			changes = append(changes, &Change{Path: path, Type: object.Type})
+		}
+	}
+	return changes
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// formatJobDiffFlat produces a diff of the job with one changed leaf per line,
+// prefixed by + for additions, - for deletions and ~ for edits, and sorted by
+// path so that the output can be searched with grep or compared with diff.
+func formatJobDiffFlat(job *api.JobDiff) string {
+	changes := flattenJobDiff(job)
+	lines := make([]string, len(changes))
+	for i, c := range changes {
+		lines[i] = formatFlatChange(c)
+	}
+
+	// Objects in a set share a path, so fall back to the whole line to keep
+	// the order stable between plans.
+	sort.Sort(flatLines{changes, lines})
+	return strings.Join(lines, "\n")
+}
+
+// formatFlatChange formats a single changed leaf.
+func formatFlatChange(c *Change) string {
+	var out string
+	switch c.Type {
+	case diffTypeAdded:
+		out = "[green]+[reset] " + c.Path
+		if c.New != "" {
+			out += fmt.Sprintf(": %q", c.New)
+		}
+	case diffTypeDeleted:
+		out = "[red]-[reset] " + c.Path
+		if c.Old != "" {
+			out += fmt.Sprintf(": %q", c.Old)
+		}
+	default:
+		out = "[light_yellow]~[reset] " + c.Path
+		if c.Old != "" || c.New != "" {
+			out += fmt.Sprintf(": %q -> %q", c.Old, c.New)
+		}
+	}
+
+	if len(c.Annotations) != 0 {
+		out += fmt.Sprintf(" (%s)", colorAnnotations(c.Annotations))
+	}
+	return out
+}
+
+// flatLines sorts formatted changes by path and then by their formatted line.
+type flatLines struct {
+	changes []*Change
+	lines   []string
+}
+
+func (f flatLines) Len() int { return len(f.lines) }
+
+func (f flatLines) Less(i, j int) bool {
+	if f.changes[i].Path != f.changes[j].Path {
+		return f.changes[i].Path < f.changes[j].Path
+	}
+	return f.lines[i] < f.lines[j]
+}
+
+func (f flatLines) Swap(i, j int) {
+	f.changes[i], f.changes[j] = f.changes[j], f.changes[i]
+	f.lines[i], f.lines[j] = f.lines[j], f.lines[i]
+}
@@ -0,0 +1,72 @@
+package nomaddiffprinter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// annotatedTaskDiff is a job diff with an edited task that has both changed
+// fields and a task-level annotation.
+func annotatedTaskDiff() *api.JobDiff {
+	return &api.JobDiff{
+		Type: "Edited",
+		ID:   "example",
+		TaskGroups: []*api.TaskGroupDiff{{
+			Type: "Edited",
+			Name: "web",
+			Fields: []*api.FieldDiff{{
+				Type: "Edited",
+				Name: "Count",
+				Old:  "1",
+				New:  "2",
+			}},
+			Tasks: []*api.TaskDiff{{
+				Type:        "Edited",
+				Name:        "app",
+				Annotations: []string{"forces create/destroy update"},
+				Objects: []*api.ObjectDiff{{
+					Type: "Edited",
+					Name: "Config",
+					Fields: []*api.FieldDiff{{
+						Type: "Edited",
+						Name: "image",
+						Old:  "redis:6",
+						New:  "redis:7",
+					}},
+				}},
+			}},
+		}},
+	}
+}
+
+func TestFormatJobDiffFlat(t *testing.T) {
+	out := stripColor(formatJobDiffFlat(annotatedTaskDiff()))
+	expected := strings.Join([]string{
+		`~ TaskGroup[web].Count: "1" -> "2"`,
+		`~ TaskGroup[web].Task[app] (forces create/destroy update)`,
+		`~ TaskGroup[web].Task[app].Config.image: "redis:6" -> "redis:7"`,
+	}, "\n")
+	if out != expected {
+		t.Errorf("unexpected output:\n%s\nexpected:\n%s", out, expected)
+	}
+}
+
+func TestFlattenJobDiffUnchangedTask(t *testing.T) {
+	diff := annotatedTaskDiff()
+	diff.TaskGroups[0].Tasks = append(diff.TaskGroups[0].Tasks, &api.TaskDiff{Type: "None", Name: "sidecar"})
+	diff.TaskGroups[0].Tasks = append(diff.TaskGroups[0].Tasks, &api.TaskDiff{Type: "Added", Name: "logger"})
+
+	var paths []string
+	for _, c := range flattenJobDiff(diff) {
+		paths = append(paths, c.Path)
+	}
+	got := strings.Join(paths, "\n")
+	if strings.Contains(got, "sidecar") {
+		t.Errorf("unchanged task was flattened:\n%s", got)
+	}
+	if !strings.Contains(got, "TaskGroup[web].Task[logger]") {
+		t.Errorf("added task is missing:\n%s", got)
+	}
+}
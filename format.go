@@ -108,3 +108,9 @@ func formatTime(t time.Time) string {
 func formatTimeDifference(first, second time.Time, d time.Duration) string {
 	return second.Truncate(d).Sub(first.Truncate(d)).String()
 }
+
+// formatKV takes a set of strings and formats them into properly
+// aligned k = v pairs.
+func formatKV(in []string) string {
+	return formatColumns(in, " = ")
+}
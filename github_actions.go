@@ -0,0 +1,191 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// GitHubActionsOptions configures WriteGitHubActions.
+type GitHubActionsOptions struct {
+	// SummaryFile is the file the step summary Markdown is appended to,
+	// usually the value of $GITHUB_STEP_SUMMARY. No summary is written if it
+	// is empty.
+	SummaryFile string
+
+	// OutputsFile is the file the key=value plan counts are appended to,
+	// usually the value of $GITHUB_OUTPUT. No outputs are written if it is
+	// empty.
+	OutputsFile string
+}
+
+// WriteGitHubActions renders the result of a plan as GitHub Actions workflow
+// commands. Placement failures are reported as errors, destructive updates and
+// preemptions as warnings and the plan warnings as notices.
+func WriteGitHubActions(w io.Writer, job *api.Job, resp *api.JobPlanResponse, opts *GitHubActionsOptions) error {
+	if opts == nil {
+		opts = &GitHubActionsOptions{}
+	}
+
+	var commands []string
+	for _, tg := range sortedTaskGroupFromMetrics(resp.FailedTGAllocs) {
+		metrics := resp.FailedTGAllocs[tg]
+		noun := "allocation"
+		if metrics.CoalescedFailures > 0 {
+			noun += "s"
+		}
+		msg := fmt.Sprintf("Task Group %q failed to place %d %s:\n%s",
+			tg, metrics.CoalescedFailures+1, noun, stripColor(formatAllocMetrics(metrics, false, "")))
+		commands = append(commands, workflowCommand("error", "Placement failure", msg))
+	}
+
+	if resp.Annotations != nil {
+		for _, tg := range planTaskGroups(resp) {
+			d := resp.Annotations.DesiredTGUpdates[tg]
+			if d == nil || d.DestructiveUpdate == 0 {
+				continue
+			}
+			msg := fmt.Sprintf("Task Group %q will have %d allocations destructively updated", tg, d.DestructiveUpdate)
+			commands = append(commands, workflowCommand("warning", "Destructive update", msg))
+		}
+		if n := len(resp.Annotations.PreemptedAllocs); n > 0 {
+			msg := fmt.Sprintf("%d allocations will be preempted", n)
+			commands = append(commands, workflowCommand("warning", "Preemption", msg))
+		}
+	}
+
+	for _, warning := range ParseWarnings(resp.Warnings) {
+		commands = append(commands, workflowCommand("notice", "Job warning", warning.Message))
+	}
+
+	for _, cmd := range commands {
+		if _, err := fmt.Fprintln(w, cmd); err != nil {
+			return err
+		}
+	}
+
+	counts := getPlanCounts(resp)
+	if opts.SummaryFile != "" {
+		if err := appendFile(opts.SummaryFile, formatStepSummary(job, resp, counts)); err != nil {
+			return err
+		}
+	}
+	if opts.OutputsFile != "" {
+		if err := appendFile(opts.OutputsFile, formatOutputs(counts)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// planCounts are the totals of the desired updates over all task groups.
+type planCounts struct {
+	Places             uint64
+	Stops              uint64
+	InPlaceUpdates     uint64
+	DestructiveUpdates uint64
+	Canaries           uint64
+	Preemptions        uint64
+	FailedGroups       int
+	ExitCode           int
+}
+
+// getPlanCounts sums the desired updates of every task group.
+func getPlanCounts(resp *api.JobPlanResponse) planCounts {
+	counts := planCounts{
+		FailedGroups: len(resp.FailedTGAllocs),
+		ExitCode:     getExitCode(resp),
+	}
+	if resp.Annotations == nil {
+		return counts
+	}
+	for _, d := range resp.Annotations.DesiredTGUpdates {
+		counts.Places += d.Place
+		counts.Stops += d.Stop
+		counts.InPlaceUpdates += d.InPlaceUpdate
+		counts.DestructiveUpdates += d.DestructiveUpdate
+		counts.Canaries += d.Canary
+		counts.Preemptions += d.Preemptions
+	}
+	return counts
+}
+
+// formatOutputs formats the plan counts as key=value lines.
+func formatOutputs(counts planCounts) string {
+	return fmt.Sprintf("places=%d\nstops=%d\nin_place_updates=%d\ndestructive_updates=%d\ncanaries=%d\npreemptions=%d\nfailed_groups=%d\nexit_code=%d\n",
+		counts.Places, counts.Stops, counts.InPlaceUpdates, counts.DestructiveUpdates,
+		counts.Canaries, counts.Preemptions, counts.FailedGroups, counts.ExitCode)
+}
+
+// formatStepSummary produces the Markdown step summary of a plan.
+func formatStepSummary(job *api.Job, resp *api.JobPlanResponse, counts planCounts) string {
+	var jobID string
+	if job.ID != nil {
+		jobID = *job.ID
+	}
+
+	out := fmt.Sprintf("### Nomad plan: `%s`\n\n", jobID)
+	out += "| Task Group | Place | Stop | In-place | Destructive | Canary | Preemptions | Placement |\n"
+	out += "| --- | ---: | ---: | ---: | ---: | ---: | ---: | --- |\n"
+	for _, tg := range planTaskGroups(resp) {
+		var d *api.DesiredUpdates
+		if resp.Annotations != nil {
+			d = resp.Annotations.DesiredTGUpdates[tg]
+		}
+		if d == nil {
+			d = &api.DesiredUpdates{}
+		}
+		placement := ":white_check_mark:"
+		if _, ok := resp.FailedTGAllocs[tg]; ok {
+			placement = ":x: failed"
+		}
+		out += fmt.Sprintf("| %s | %d | %d | %d | %d | %d | %d | %s |\n",
+			tg, d.Place, d.Stop, d.InPlaceUpdate, d.DestructiveUpdate, d.Canary, d.Preemptions, placement)
+	}
+	out += fmt.Sprintf("\nExit code: `%d`\n\n", counts.ExitCode)
+
+	if resp.Diff != nil {
+		out += "<details><summary>Job diff</summary>\n\n```\n"
+		out += strings.TrimSpace(stripColor(formatJobDiff(resp.Diff, false, nil)))
+		out += "\n```\n\n</details>\n\n"
+	}
+	out += "<details><summary>Scheduler dry-run</summary>\n\n```\n"
+	out += stripColor(formatDryRun(resp, job))
+	out += "\n```\n\n</details>\n"
+	return out
+}
+
+// workflowCommand formats a GitHub Actions workflow command.
+func workflowCommand(command, title, msg string) string {
+	return fmt.Sprintf("::%s title=%s::%s", command, escapeWorkflowProperty(title), escapeWorkflowData(msg))
+}
+
+// escapeWorkflowData escapes the message of a workflow command.
+func escapeWorkflowData(s string) string {
+	s = strings.ReplaceAll(s, "%", "%25")
+	s = strings.ReplaceAll(s, "\r", "%0D")
+	return strings.ReplaceAll(s, "\n", "%0A")
+}
+
+// escapeWorkflowProperty escapes a property value of a workflow command.
+func escapeWorkflowProperty(s string) string {
+	s = escapeWorkflowData(s)
+	s = strings.ReplaceAll(s, ":", "%3A")
+	return strings.ReplaceAll(s, ",", "%2C")
+}
+
+// appendFile appends s to the file at path, creating it if needed.
+func appendFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(s); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,70 @@
+package nomaddiffprinter
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestWriteGitHubActionsNoAnnotations(t *testing.T) {
+	job := testJob("example", "default")
+	resp := &api.JobPlanResponse{
+		Diff: &api.JobDiff{Type: diffTypeNone, ID: "example"},
+		FailedTGAllocs: map[string]*api.AllocationMetric{
+			"web": {CoalescedFailures: 1},
+		},
+	}
+
+	dir := t.TempDir()
+	opts := &GitHubActionsOptions{
+		SummaryFile: filepath.Join(dir, "summary.md"),
+		OutputsFile: filepath.Join(dir, "outputs"),
+	}
+	var out bytes.Buffer
+	if err := WriteGitHubActions(&out, job, resp, opts); err != nil {
+		t.Fatalf("error writing GitHub Actions output: %v", err)
+	}
+	if !strings.Contains(out.String(), "::error title=Placement failure::") {
+		t.Errorf("expected a placement failure error:\n%s", out.String())
+	}
+
+	summary, err := ioutil.ReadFile(opts.SummaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summary), "| web | 0 | 0 | 0 | 0 | 0 | 0 | :x: failed |") {
+		t.Errorf("expected a row for the failed task group:\n%s", summary)
+	}
+
+	outputs, err := ioutil.ReadFile(opts.OutputsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(outputs), "failed_groups=1\n") || !strings.Contains(string(outputs), "exit_code=0\n") {
+		t.Errorf("unexpected outputs:\n%s", outputs)
+	}
+}
+
+func TestGetPlanCounts(t *testing.T) {
+	resp := &api.JobPlanResponse{
+		Annotations: &api.PlanAnnotations{
+			DesiredTGUpdates: map[string]*api.DesiredUpdates{
+				"web": {Place: 2, DestructiveUpdate: 1},
+				"api": {Stop: 1, InPlaceUpdate: 3},
+			},
+		},
+	}
+	counts := getPlanCounts(resp)
+	expected := planCounts{Places: 2, Stops: 1, InPlaceUpdates: 3, DestructiveUpdates: 1, ExitCode: 1}
+	if counts != expected {
+		t.Errorf("getPlanCounts = %+v, expected %+v", counts, expected)
+	}
+
+	if counts := getPlanCounts(&api.JobPlanResponse{}); counts != (planCounts{}) {
+		t.Errorf("expected no counts without annotations, got %+v", counts)
+	}
+}
@@ -4,7 +4,8 @@ go 1.16
 
 require (
 	github.com/hashicorp/nomad/api v0.0.0-20210827125435-42666b801437
+	github.com/mattn/go-runewidth v0.0.13
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
-	github.com/ryanuber/columnize v2.1.2+incompatible
+	github.com/rivo/uniseg v0.2.0
 	golang.org/x/term v0.0.0-20210615171337-6886f2dfbf5b
 )
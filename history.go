@@ -0,0 +1,124 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// PrintJobHistory prints every registered version of a job, newest first,
+// along with the diff between it and the version that preceded it. The diffs
+// are computed by the server. The versions are returned in the order they
+// were printed.
+func PrintJobHistory(client *api.Client, jobID string, q *api.QueryOptions, output io.Writer) ([]*api.Job, error) {
+	return PrintJobHistoryContext(context.Background(), client, jobID, q, output)
+}
+
+// PrintJobHistoryContext is like PrintJobHistory but the lookup of the job
+// versions is bound to ctx.
+func PrintJobHistoryContext(ctx context.Context, client *api.Client, jobID string, q *api.QueryOptions, output io.Writer) ([]*api.Job, error) {
+	versions, diffs, _, err := client.Jobs().Versions(jobID, true, q.WithContext(ctx))
+	if err != nil {
+		return nil, contextError(ctx, "job versions", err)
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	for i, version := range versions {
+		// The oldest version has no prior version to be compared with.
+		var diff *api.JobDiff
+		if i < len(diffs) {
+			diff = diffs[i]
+		}
+		outputJobVersion(version, diff, print)
+	}
+	return versions, nil
+}
+
+// PrintJobVersionDiff prints the diff between two versions of a job. The
+// diff is computed locally with DiffJobs so that any two versions may be
+// compared, not just consecutive ones. It names fields and objects the same
+// way as the server diffs printed by PrintJobHistory, but has none of their
+// annotations, such as "forces create/destroy update", since those are only
+// computed by the scheduler.
+func PrintJobVersionDiff(client *api.Client, jobID string, from, to uint64, q *api.QueryOptions, output io.Writer) (*api.JobDiff, error) {
+	return PrintJobVersionDiffContext(context.Background(), client, jobID, from, to, q, output)
+}
+
+// PrintJobVersionDiffContext is like PrintJobVersionDiff but the lookup of the
+// job versions is bound to ctx.
+func PrintJobVersionDiffContext(ctx context.Context, client *api.Client, jobID string, from, to uint64, q *api.QueryOptions, output io.Writer) (*api.JobDiff, error) {
+	versions, _, _, err := client.Jobs().Versions(jobID, false, q.WithContext(ctx))
+	if err != nil {
+		return nil, contextError(ctx, "job versions", err)
+	}
+
+	fromJob, err := findJobVersion(versions, from)
+	if err != nil {
+		return nil, err
+	}
+	toJob, err := findJobVersion(versions, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := DiffJobs(fromJob, toJob)
+	if err != nil {
+		return nil, err
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	print(colorize().Color(fmt.Sprintf("[bold]Comparing version %d to version %d[reset]\n", from, to)))
+	outputJobVersion(toJob, diff, print)
+	return diff, nil
+}
+
+// outputJobVersion prints the version annotations of a job followed by its
+// diff, if any.
+func outputJobVersion(job *api.Job, diff *api.JobDiff, print func(string)) {
+	var stable bool
+	if job.Stable != nil {
+		stable = *job.Stable
+	}
+	var submitTime string
+	if job.SubmitTime != nil {
+		submitTime = formatTime(time.Unix(0, *job.SubmitTime))
+	}
+
+	print(colorize().Color(formatKV([]string{
+		fmt.Sprintf("[bold]Version[reset]|%d", jobVersion(job)),
+		fmt.Sprintf("[bold]Stable[reset]|%t", stable),
+		fmt.Sprintf("[bold]Submit Date[reset]|%s", submitTime),
+	})))
+
+	if diff == nil || diff.Type == diffTypeNone {
+		print("")
+		return
+	}
+	print(fmt.Sprintf("%s\n",
+		colorize().Color(strings.TrimSpace(formatJobDiff(diff, false, nil)))))
+}
+
+// findJobVersion returns the job with the given version.
+func findJobVersion(versions []*api.Job, version uint64) (*api.Job, error) {
+	for _, job := range versions {
+		if jobVersion(job) == version {
+			return job, nil
+		}
+	}
+	return nil, fmt.Errorf("job version %d not found", version)
+}
+
+func jobVersion(job *api.Job) uint64 {
+	if job.Version == nil {
+		return 0
+	}
+	return *job.Version
+}
@@ -0,0 +1,62 @@
+package nomaddiffprinter
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestPrintJobVersionDiffMatchesHistory(t *testing.T) {
+	v0 := redisJobspec()
+	v1 := redisJobspec()
+	v1.Constrain(api.NewConstraint("${attr.kernel.name}", "=", "linux"))
+	for i, job := range []*api.Job{v0, v1} {
+		version := uint64(i)
+		job.Version = &version
+	}
+
+	serverDiff := &api.JobDiff{
+		Type: diffTypeEdited,
+		ID:   "example",
+		Objects: []*api.ObjectDiff{{
+			Type: diffTypeAdded,
+			Name: "Constraint",
+			Fields: []*api.FieldDiff{
+				{Type: diffTypeAdded, Name: "LTarget", New: "${attr.kernel.name}"},
+				{Type: diffTypeAdded, Name: "Operand", New: "="},
+				{Type: diffTypeAdded, Name: "RTarget", New: "linux"},
+			},
+		}},
+	}
+	client := testClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(&api.JobVersionsResponse{
+			Versions: []*api.Job{v1, v0},
+			Diffs:    []*api.JobDiff{serverDiff},
+		})
+	}))
+
+	var history, local bytes.Buffer
+	if _, err := PrintJobHistory(client, "example", nil, &history); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := PrintJobVersionDiff(client, "example", 0, 1, nil, &local); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both diffs print the added constraint the same way.
+	section := func(s string) string {
+		s = stripColor(s)
+		start := strings.Index(s, "+ Constraint {")
+		if start < 0 {
+			t.Fatalf("missing added constraint in:\n%s", s)
+		}
+		return s[start : start+strings.Index(s[start:], "}")+1]
+	}
+	if h, l := section(history.String()), section(local.String()); h != l {
+		t.Errorf("local diff doesn't match the server diff:\n%s\nexpected:\n%s", l, h)
+	}
+}
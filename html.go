@@ -0,0 +1,191 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+	"github.com/mitchellh/colorstring"
+)
+
+// WriteHTMLReport renders the result of a plan as a self-contained HTML
+// document. Task groups, tasks and objects are collapsible sections and the
+// scheduler dry-run, warnings and preemptions are rendered as panels.
+func WriteHTMLReport(w io.Writer, job *api.Job, resp *api.JobPlanResponse) error {
+	var preemptions []string
+	if resp.Annotations != nil && len(resp.Annotations.PreemptedAllocs) > 0 {
+		addPreemptions(resp, func(s string) {
+			preemptions = append(preemptions, s)
+		}, nil)
+		// The panel has its own heading
+		preemptions = preemptions[1:]
+	}
+
+	report := struct {
+		Diff        *api.JobDiff
+		DryRun      string
+		Warnings    string
+		Preemptions string
+		ExitCode    int
+	}{
+		Diff:        resp.Diff,
+		DryRun:      stripColor(formatDryRun(resp, job)),
+		Warnings:    resp.Warnings,
+		Preemptions: stripColor(strings.Join(preemptions, "\n")),
+		ExitCode:    getExitCode(resp),
+	}
+	return htmlReportTemplate.Execute(w, report)
+}
+
+// stripColor removes the color annotations from a string.
+func stripColor(s string) string {
+	c := &colorstring.Colorize{
+		Colors:  colorstring.DefaultColors,
+		Disable: true,
+	}
+	return c.Color(s)
+}
+
+// htmlLine is a row of the side-by-side view of a multi-line value.
+type htmlLine struct {
+	Old, New string
+	Changed  bool
+}
+
+// sideBySide splits two multi-line values into aligned rows.
+func sideBySide(old, new string) []htmlLine {
+	oldLines := strings.Split(old, "\n")
+	newLines := strings.Split(new, "\n")
+	n := len(oldLines)
+	if len(newLines) > n {
+		n = len(newLines)
+	}
+
+	lines := make([]htmlLine, n)
+	for i := range lines {
+		if i < len(oldLines) {
+			lines[i].Old = oldLines[i]
+		}
+		if i < len(newLines) {
+			lines[i].New = newLines[i]
+		}
+		lines[i].Changed = lines[i].Old != lines[i].New
+	}
+	return lines
+}
+
+var htmlFuncs = template.FuncMap{
+	"diffClass": func(diffType string) string {
+		return strings.ToLower(diffType)
+	},
+	"marker": func(diffType string) string {
+		marker, _ := getDiffString(diffType)
+		return strings.TrimSpace(stripColor(marker))
+	},
+	"updateClass": func(updateType string) string {
+		return strings.Trim(getUpdateColor(updateType), "[]")
+	},
+	"annotationClass": func(annotation string) string {
+		return strings.Trim(getAnnotationColor(annotation), "[]")
+	},
+	"multiline": func(f *api.FieldDiff) bool {
+		return strings.Contains(f.Old, "\n") || strings.Contains(f.New, "\n")
+	},
+	"sideBySide": sideBySide,
+	"quote": func(s string) string {
+		return fmt.Sprintf("%q", s)
+	},
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(htmlFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Nomad plan{{with .Diff}}: {{.ID}}{{end}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; margin: 2em; color: #24292e; }
+pre, code, .field { font-family: SFMono-Regular, Consolas, Menlo, monospace; font-size: 13px; }
+details { margin: 0.25em 0 0.25em 1.25em; }
+summary { cursor: pointer; }
+.field { margin-left: 1.25em; white-space: pre-wrap; }
+.marker { display: inline-block; min-width: 2.5em; font-weight: bold; }
+.added > .marker, .added > summary > .marker, .green { color: #22863a; }
+.deleted > .marker, .deleted > summary > .marker, .red { color: #cb2431; }
+.edited > .marker, .edited > summary > .marker, .light_yellow { color: #b08800; }
+.yellow { color: #e36209; }
+.blue { color: #0366d6; }
+.cyan { color: #1b7c83; }
+.badge { display: inline-block; border: 1px solid currentColor; border-radius: 1em; padding: 0 0.6em; margin-left: 0.3em; font-size: 12px; }
+.annotation { font-size: 12px; margin-left: 0.5em; }
+.panel { border: 1px solid #e1e4e8; border-left-width: 4px; border-radius: 4px; padding: 0.5em 1em; margin: 1em 0; }
+.panel h2 { font-size: 15px; margin: 0.25em 0; }
+.panel.dry-run { border-left-color: #22863a; }
+.panel.warnings { border-left-color: #e36209; }
+.panel.preemptions { border-left-color: #cb2431; }
+table.sbs { border-collapse: collapse; margin: 0.25em 0 0.5em 2.5em; }
+table.sbs th, table.sbs td { border: 1px solid #e1e4e8; padding: 0 0.5em; vertical-align: top; white-space: pre; }
+table.sbs tr.changed td.old { background: #ffeef0; }
+table.sbs tr.changed td.new { background: #e6ffed; }
+</style>
+</head>
+<body>
+{{with .Diff}}
+<h1 class="{{diffClass .Type}}"><span class="marker">{{marker .Type}}</span>Job: {{quote .ID}}</h1>
+{{template "fieldsAndObjects" .}}
+{{range .TaskGroups}}
+<details class="{{diffClass .Type}}" open>
+<summary><span class="marker">{{marker .Type}}</span><strong>Task Group: {{quote .Name}}</strong>
+{{- range $type, $count := .Updates}} <span class="badge {{updateClass $type}}">{{$count}} {{$type}}</span>{{end}}</summary>
+{{template "fieldsAndObjects" .}}
+{{range .Tasks}}
+<details class="{{diffClass .Type}}" open>
+<summary><span class="marker">{{marker .Type}}</span><strong>Task: {{quote .Name}}</strong>
+{{- range .Annotations}} <span class="annotation {{annotationClass .}}">{{.}}</span>{{end}}</summary>
+{{template "fieldsAndObjects" .}}
+</details>
+{{end}}
+</details>
+{{end}}
+{{end}}
+<div class="panel dry-run">
+<h2>Scheduler dry-run</h2>
+<pre>{{.DryRun}}</pre>
+</div>
+{{if .Warnings}}
+<div class="panel warnings">
+<h2>Job Warnings</h2>
+<pre>{{.Warnings}}</pre>
+</div>
+{{end}}
+{{if .Preemptions}}
+<div class="panel preemptions">
+<h2>Preemptions</h2>
+<pre>{{.Preemptions}}</pre>
+</div>
+{{end}}
+<p>Exit code: <code>{{.ExitCode}}</code></p>
+</body>
+</html>
+{{define "fieldsAndObjects"}}
+{{range .Fields}}{{template "field" .}}{{end}}
+{{range .Objects}}{{template "object" .}}{{end}}
+{{end}}
+{{define "field"}}
+<div class="field {{diffClass .Type}}"><span class="marker">{{marker .Type}}</span>{{.Name}}:
+{{- if multiline .}}</div>
+<table class="sbs"><tr><th>Old</th><th>New</th></tr>
+{{range sideBySide .Old .New}}<tr{{if .Changed}} class="changed"{{end}}><td class="old">{{.Old}}</td><td class="new">{{.New}}</td></tr>
+{{end}}</table>
+{{- else}} {{if eq .Type "Added"}}{{quote .New}}{{else if eq .Type "Deleted"}}{{quote .Old}}{{else if eq .Type "Edited"}}{{quote .Old}} =&gt; {{quote .New}}{{else}}{{quote .New}}{{end}}
+{{- range .Annotations}} <span class="annotation {{annotationClass .}}">{{.}}</span>{{end}}</div>
+{{- end}}
+{{end}}
+{{define "object"}}
+<details class="{{diffClass .Type}}" open>
+<summary><span class="marker">{{marker .Type}}</span>{{.Name}}</summary>
+{{template "fieldsAndObjects" .}}
+</details>
+{{end}}
+`))
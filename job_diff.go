@@ -0,0 +1,593 @@
+package nomaddiffprinter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	diffTypeNone    = "None"
+	diffTypeAdded   = "Added"
+	diffTypeDeleted = "Deleted"
+	diffTypeEdited  = "Edited"
+)
+
+// jobDiffSkipFields are fields of the job that are set by the server or that
+// are never returned by it, and so should not be compared.
+var jobDiffSkipFields = map[string]bool{
+	"ID":                true,
+	"TaskGroups":        true,
+	"ConsulToken":       true,
+	"VaultToken":        true,
+	"ParentID":          true,
+	"Dispatched":        true,
+	"Payload":           true,
+	"NomadTokenID":      true,
+	"Status":            true,
+	"StatusDescription": true,
+	"Stable":            true,
+	"Version":           true,
+	"SubmitTime":        true,
+	"CreateIndex":       true,
+	"ModifyIndex":       true,
+	"JobModifyIndex":    true,
+}
+
+var (
+	taskGroupDiffSkipFields = map[string]bool{"Name": true, "Tasks": true}
+	taskDiffSkipFields      = map[string]bool{"Name": true}
+)
+
+// diffObjectNames maps the fields of the api structs holding several objects
+// to the name the Nomad server gives each of those objects in a diff.
+var diffObjectNames = map[string]string{
+	"Affinities":      "Affinity",
+	"Artifacts":       "Artifact",
+	"Checks":          "Check",
+	"Constraints":     "Constraint",
+	"Devices":         "Device",
+	"Networks":        "Network",
+	"ScalingPolicies": "ScalingPolicy",
+	"Services":        "Service",
+	"Spreads":         "Spread",
+	"Templates":       "Template",
+	"VolumeMounts":    "VolumeMount",
+	"Volumes":         "Volume",
+}
+
+// DiffJobs computes the difference between two versions of a job locally,
+// without contacting a Nomad server. Both jobs are canonicalized on a copy
+// before being compared so that unset defaults do not show up as changes, and
+// a job returned by the server can be compared with its jobspec. Objects are
+// named the same way as in the diffs of the server, such as Constraint for an
+// element of Constraints. A nil old job produces a diff where everything is
+// added.
+func DiffJobs(old, new *api.Job) (*api.JobDiff, error) {
+	if new == nil {
+		return nil, fmt.Errorf("must pass non-nil job")
+	}
+	newCopy, err := canonicalizedCopy(new)
+	if err != nil {
+		return nil, err
+	}
+	normalizeJob(newCopy)
+	var oldCopy *api.Job
+	if old != nil {
+		if oldCopy, err = canonicalizedCopy(old); err != nil {
+			return nil, err
+		}
+		normalizeJob(oldCopy)
+	}
+	return diffJobs(oldCopy, newCopy), nil
+}
+
+// normalizeJob applies the defaults the server sets when a job is registered
+// but that Canonicalize doesn't, so that they don't show up as changes.
+func normalizeJob(job *api.Job) {
+	// Only the stagger and max parallel of the update strategy of the job are
+	// kept by the server, the rest is set on the task groups.
+	if u := job.Update; u != nil {
+		job.Update = &api.UpdateStrategy{Stagger: u.Stagger, MaxParallel: u.MaxParallel}
+	}
+
+	for _, tg := range job.TaskGroups {
+		normalizeNetworks(tg.Networks)
+		for _, task := range tg.Tasks {
+			if task.Resources != nil {
+				normalizeNetworks(task.Resources.Networks)
+			}
+		}
+	}
+}
+
+// normalizeNetworks sets the host network of ports to the default one.
+func normalizeNetworks(networks []*api.NetworkResource) {
+	for _, network := range networks {
+		if network == nil {
+			continue
+		}
+		for _, ports := range [][]api.Port{network.ReservedPorts, network.DynamicPorts} {
+			for i := range ports {
+				if ports[i].HostNetwork == "" {
+					ports[i].HostNetwork = "default"
+				}
+			}
+		}
+	}
+}
+
+// canonicalizedCopy returns a deep copy of the job with defaults applied.
+func canonicalizedCopy(job *api.Job) (*api.Job, error) {
+	out, err := copyJob(job)
+	if err != nil {
+		return nil, err
+	}
+	out.Canonicalize()
+	return out, nil
+}
+
+// copyJob returns a deep copy of the job.
+func copyJob(job *api.Job) (*api.Job, error) {
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("error copying job: %v", err)
+	}
+	var out api.Job
+	if err := json.Unmarshal(buf, &out); err != nil {
+		return nil, fmt.Errorf("error copying job: %v", err)
+	}
+	return &out, nil
+}
+
+func diffJobs(old, new *api.Job) *api.JobDiff {
+	diff := &api.JobDiff{Type: diffTypeNone, ID: stringValue(new.ID)}
+
+	var oldValue reflect.Value
+	var oldGroups []*api.TaskGroup
+	if old == nil {
+		diff.Type = diffTypeAdded
+	} else {
+		oldValue = reflect.ValueOf(*old)
+		oldGroups = old.TaskGroups
+	}
+	diff.Fields, diff.Objects = diffStructs(oldValue, reflect.ValueOf(*new), jobDiffSkipFields)
+
+	oldByName := make(map[string]*api.TaskGroup, len(oldGroups))
+	for _, tg := range oldGroups {
+		oldByName[stringValue(tg.Name)] = tg
+	}
+	newByName := make(map[string]*api.TaskGroup, len(new.TaskGroups))
+	for _, tg := range new.TaskGroups {
+		newByName[stringValue(tg.Name)] = tg
+	}
+	for _, name := range unionKeys(oldByName, newByName) {
+		if tgDiff := diffTaskGroups(name, oldByName[name], newByName[name]); tgDiff != nil {
+			diff.TaskGroups = append(diff.TaskGroups, tgDiff)
+		}
+	}
+
+	if diff.Type == diffTypeNone && (len(diff.Fields) > 0 || len(diff.Objects) > 0 || len(diff.TaskGroups) > 0) {
+		diff.Type = diffTypeEdited
+	}
+	return diff
+}
+
+// diffTaskGroups returns the diff of two task groups sharing a name or nil if
+// they are identical.
+func diffTaskGroups(name string, old, new *api.TaskGroup) *api.TaskGroupDiff {
+	diff := &api.TaskGroupDiff{Name: name}
+
+	var oldValue, newValue reflect.Value
+	var oldTasks, newTasks []*api.Task
+	if old != nil {
+		oldValue = reflect.ValueOf(*old)
+		oldTasks = old.Tasks
+	}
+	if new != nil {
+		newValue = reflect.ValueOf(*new)
+		newTasks = new.Tasks
+	}
+	diff.Fields, diff.Objects = diffStructs(oldValue, newValue, taskGroupDiffSkipFields)
+
+	oldByName := make(map[string]*api.Task, len(oldTasks))
+	for _, task := range oldTasks {
+		oldByName[task.Name] = task
+	}
+	newByName := make(map[string]*api.Task, len(newTasks))
+	for _, task := range newTasks {
+		newByName[task.Name] = task
+	}
+	for _, taskName := range unionKeys(oldByName, newByName) {
+		if taskDiff := diffTasks(taskName, oldByName[taskName], newByName[taskName]); taskDiff != nil {
+			diff.Tasks = append(diff.Tasks, taskDiff)
+		}
+	}
+
+	diff.Type = diffType(old != nil, new != nil)
+	if diff.Type == diffTypeEdited && len(diff.Fields) == 0 && len(diff.Objects) == 0 && len(diff.Tasks) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffTasks returns the diff of two tasks sharing a name or nil if they are
+// identical.
+func diffTasks(name string, old, new *api.Task) *api.TaskDiff {
+	diff := &api.TaskDiff{Name: name}
+
+	var oldValue, newValue reflect.Value
+	if old != nil {
+		oldValue = reflect.ValueOf(*old)
+	}
+	if new != nil {
+		newValue = reflect.ValueOf(*new)
+	}
+	diff.Fields, diff.Objects = diffStructs(oldValue, newValue, taskDiffSkipFields)
+
+	diff.Type = diffType(old != nil, new != nil)
+	if diff.Type == diffTypeEdited && len(diff.Fields) == 0 && len(diff.Objects) == 0 {
+		return nil
+	}
+	return diff
+}
+
+// diffStructs walks the exported fields of two structs of the same type and
+// returns the changed primitive fields and nested objects. Either value may be
+// the zero reflect.Value to denote a missing struct.
+func diffStructs(old, new reflect.Value, skip map[string]bool) ([]*api.FieldDiff, []*api.ObjectDiff) {
+	v := old
+	if !v.IsValid() {
+		v = new
+	}
+	t := v.Type()
+
+	var fields []*api.FieldDiff
+	var objects []*api.ObjectDiff
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.PkgPath != "" || skip[sf.Name] {
+			continue
+		}
+		o, n := structField(old, i), structField(new, i)
+		f, obj := diffValues(sf.Name, sf.Type, o, n)
+		fields = append(fields, f...)
+		objects = append(objects, obj...)
+	}
+
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Name < fields[j].Name })
+	sort.SliceStable(objects, func(i, j int) bool { return objects[i].Name < objects[j].Name })
+	return fields, objects
+}
+
+// diffValues diffs a single named value of type t.
+func diffValues(name string, t reflect.Type, old, new reflect.Value) ([]*api.FieldDiff, []*api.ObjectDiff) {
+	elem := t
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	old, new = indirect(old), indirect(new)
+
+	switch elem.Kind() {
+	case reflect.Struct:
+		if obj := diffObject(name, old, new); obj != nil {
+			return nil, []*api.ObjectDiff{obj}
+		}
+		return nil, nil
+
+	case reflect.Slice:
+		if elem.Elem().Kind() == reflect.Uint8 {
+			return fieldDiffs(name, primitiveString(old), primitiveString(new)), nil
+		}
+		if isPrimitive(elem.Elem()) {
+			if obj := diffPrimitiveSet(name, old, new); obj != nil {
+				return nil, []*api.ObjectDiff{obj}
+			}
+			return nil, nil
+		}
+		return nil, diffObjectSet(objectName(name), old, new)
+
+	case reflect.Map:
+		if isPrimitive(elem.Elem()) {
+			return diffPrimitiveMap(name, old, new), nil
+		}
+		if obj := diffFlattened(objectName(name), old, new); obj != nil {
+			return nil, []*api.ObjectDiff{obj}
+		}
+		return nil, nil
+
+	case reflect.Interface:
+		if obj := diffFlattened(name, old, new); obj != nil {
+			return nil, []*api.ObjectDiff{obj}
+		}
+		return nil, nil
+
+	default:
+		// The server returns unset values as their zero value.
+		if isZero(old) && isZero(new) {
+			return nil, nil
+		}
+		return fieldDiffs(name, primitiveString(old), primitiveString(new)), nil
+	}
+}
+
+// objectName returns the name of the objects held by a field in a diff.
+func objectName(field string) string {
+	if name, ok := diffObjectNames[field]; ok {
+		return name
+	}
+	return field
+}
+
+// diffObject diffs two structs of the same type as a named object.
+func diffObject(name string, old, new reflect.Value) *api.ObjectDiff {
+	if !old.IsValid() && !new.IsValid() {
+		return nil
+	}
+	fields, objects := diffStructs(old, new, nil)
+	if len(fields) == 0 && len(objects) == 0 {
+		return nil
+	}
+	return &api.ObjectDiff{
+		Type:    diffType(old.IsValid(), new.IsValid()),
+		Name:    name,
+		Fields:  fields,
+		Objects: objects,
+	}
+}
+
+// diffPrimitiveSet diffs two slices of primitives, ignoring ordering. Each
+// added or removed element is a field of the returned object.
+func diffPrimitiveSet(name string, old, new reflect.Value) *api.ObjectDiff {
+	oldSet := make(map[string]bool)
+	newSet := make(map[string]bool)
+	for i := 0; old.IsValid() && i < old.Len(); i++ {
+		oldSet[primitiveString(old.Index(i))] = true
+	}
+	for i := 0; new.IsValid() && i < new.Len(); i++ {
+		newSet[primitiveString(new.Index(i))] = true
+	}
+
+	var fields []*api.FieldDiff
+	for _, v := range unionKeys(oldSet, newSet) {
+		switch {
+		case !oldSet[v]:
+			fields = append(fields, &api.FieldDiff{Type: diffTypeAdded, Name: name, New: v})
+		case !newSet[v]:
+			fields = append(fields, &api.FieldDiff{Type: diffTypeDeleted, Name: name, Old: v})
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &api.ObjectDiff{
+		Type:   diffType(len(oldSet) != 0, len(newSet) != 0),
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+// diffObjectSet diffs two slices of structs, ignoring ordering. Elements that
+// are present in both slices are omitted, others are returned as added or
+// deleted objects.
+func diffObjectSet(name string, old, new reflect.Value) []*api.ObjectDiff {
+	var objects []*api.ObjectDiff
+	for i := 0; old.IsValid() && i < old.Len(); i++ {
+		if o := indirect(old.Index(i)); o.IsValid() && !containsEqual(new, o) {
+			if obj := diffObject(name, o, reflect.Value{}); obj != nil {
+				objects = append(objects, obj)
+			}
+		}
+	}
+	for i := 0; new.IsValid() && i < new.Len(); i++ {
+		if n := indirect(new.Index(i)); n.IsValid() && !containsEqual(old, n) {
+			if obj := diffObject(name, reflect.Value{}, n); obj != nil {
+				objects = append(objects, obj)
+			}
+		}
+	}
+	return objects
+}
+
+// diffPrimitiveMap diffs maps with primitive values, returning a field per
+// changed key named "name[key]".
+func diffPrimitiveMap(name string, old, new reflect.Value) []*api.FieldDiff {
+	oldMap := make(map[string]string)
+	newMap := make(map[string]string)
+	for _, k := range mapKeys(old) {
+		oldMap[k] = primitiveString(old.MapIndex(reflect.ValueOf(k).Convert(old.Type().Key())))
+	}
+	for _, k := range mapKeys(new) {
+		newMap[k] = primitiveString(new.MapIndex(reflect.ValueOf(k).Convert(new.Type().Key())))
+	}
+
+	var fields []*api.FieldDiff
+	for _, k := range unionKeys(oldMap, newMap) {
+		fields = append(fields, fieldDiffs(fmt.Sprintf("%s[%s]", name, k), oldMap[k], newMap[k])...)
+	}
+	return fields
+}
+
+// diffFlattened diffs arbitrary values, such as a task's driver config, by
+// flattening them into dotted keys.
+func diffFlattened(name string, old, new reflect.Value) *api.ObjectDiff {
+	oldFlat := make(map[string]string)
+	newFlat := make(map[string]string)
+	flatten("", old, oldFlat)
+	flatten("", new, newFlat)
+
+	var fields []*api.FieldDiff
+	for _, k := range unionKeys(oldFlat, newFlat) {
+		fields = append(fields, fieldDiffs(k, oldFlat[k], newFlat[k])...)
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	return &api.ObjectDiff{
+		Type:   diffType(len(oldFlat) != 0, len(newFlat) != 0),
+		Name:   name,
+		Fields: fields,
+	}
+}
+
+// flatten walks v and stores every leaf value in out keyed by its path.
+// Nested maps are joined with "." and slice elements use "[i]".
+func flatten(prefix string, v reflect.Value, out map[string]string) {
+	v = indirect(v)
+	if !v.IsValid() {
+		return
+	}
+	switch v.Kind() {
+	case reflect.Map:
+		for _, k := range mapKeys(v) {
+			key := k
+			if prefix != "" {
+				key = prefix + "." + k
+			}
+			flatten(key, v.MapIndex(reflect.ValueOf(k).Convert(v.Type().Key())), out)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			flatten(fmt.Sprintf("%s[%d]", prefix, i), v.Index(i), out)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if sf := v.Type().Field(i); sf.PkgPath == "" {
+				key := sf.Name
+				if prefix != "" {
+					key = prefix + "." + sf.Name
+				}
+				flatten(key, v.Field(i), out)
+			}
+		}
+	default:
+		out[prefix] = primitiveString(v)
+	}
+}
+
+// fieldDiffs returns a single element slice containing the diff of a field or
+// nil if the values are equal. Empty values are considered unset.
+func fieldDiffs(name, old, new string) []*api.FieldDiff {
+	if old == new {
+		return nil
+	}
+	return []*api.FieldDiff{{
+		Type: diffType(old != "", new != ""),
+		Name: name,
+		Old:  old,
+		New:  new,
+	}}
+}
+
+// diffType returns the diff type given whether the old and new values exist.
+func diffType(hasOld, hasNew bool) string {
+	switch {
+	case !hasOld && hasNew:
+		return diffTypeAdded
+	case hasOld && !hasNew:
+		return diffTypeDeleted
+	case hasOld && hasNew:
+		return diffTypeEdited
+	default:
+		return diffTypeNone
+	}
+}
+
+func structField(v reflect.Value, i int) reflect.Value {
+	if !v.IsValid() {
+		return reflect.Value{}
+	}
+	return v.Field(i)
+}
+
+// indirect dereferences pointers and interfaces, returning the zero
+// reflect.Value for nil values.
+func indirect(v reflect.Value) reflect.Value {
+	for v.IsValid() && (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+func isPrimitive(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// primitiveString formats a primitive value, returning an empty string for
+// unset values.
+func primitiveString(v reflect.Value) string {
+	v = indirect(v)
+	if !v.IsValid() {
+		return ""
+	}
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+		return string(v.Bytes())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// containsEqual returns whether the slice contains a struct without any
+// difference from v.
+func containsEqual(slice, v reflect.Value) bool {
+	for i := 0; slice.IsValid() && i < slice.Len(); i++ {
+		if e := indirect(slice.Index(i)); e.IsValid() && diffObject("", e, v) == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// isZero returns whether v is unset or the zero value of its type.
+func isZero(v reflect.Value) bool {
+	v = indirect(v)
+	return !v.IsValid() || v.IsZero()
+}
+
+// mapKeys returns the sorted string representation of the keys of a map.
+func mapKeys(m reflect.Value) []string {
+	if !m.IsValid() {
+		return nil
+	}
+	keys := make([]string, 0, m.Len())
+	for _, k := range m.MapKeys() {
+		keys = append(keys, fmt.Sprint(k.Interface()))
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// unionKeys returns the sorted union of the keys of two maps keyed by string.
+func unionKeys(a, b interface{}) []string {
+	seen := make(map[string]bool)
+	for _, m := range []interface{}{a, b} {
+		for _, k := range reflect.ValueOf(m).MapKeys() {
+			seen[k.String()] = true
+		}
+	}
+	keys := make([]string, 0, len(seen))
+	for k := range seen {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
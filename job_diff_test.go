@@ -0,0 +1,154 @@
+package nomaddiffprinter
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// redisJobspec returns the job of testdata/server_job.json as it is written
+// in its jobspec, without any of the defaults set by the server.
+func redisJobspec() *api.Job {
+	cpu, memory := 500, 256
+	task := api.NewTask("redis", "docker").
+		SetConfig("image", "redis:6").
+		SetConfig("ports", []string{"db"}).
+		Require(&api.Resources{CPU: &cpu, MemoryMB: &memory})
+	tg := api.NewTaskGroup("cache", 1).AddTask(task)
+	tg.Networks = []*api.NetworkResource{{DynamicPorts: []api.Port{{Label: "db", To: 6379}}}}
+
+	job := api.NewServiceJob("example", "example", "global", 50)
+	job.AddDatacenter("dc1")
+	job.AddTaskGroup(tg)
+	return job
+}
+
+// serverJob returns the job registered from redisJobspec as returned by
+// Jobs().Info.
+func serverJob(t *testing.T) *api.Job {
+	t.Helper()
+	buf, err := ioutil.ReadFile("testdata/server_job.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var job api.Job
+	if err := json.Unmarshal(buf, &job); err != nil {
+		t.Fatal(err)
+	}
+	return &job
+}
+
+func assertNoDiff(t *testing.T, diff *api.JobDiff) {
+	t.Helper()
+	if diff.Type != diffTypeNone || len(diff.Fields) != 0 || len(diff.Objects) != 0 || len(diff.TaskGroups) != 0 {
+		t.Errorf("expected no differences, got a diff of type %s:\n%s", diff.Type, stripColor(formatJobDiffFlat(diff)))
+	}
+}
+
+func TestDiffJobsIdentical(t *testing.T) {
+	diff, err := DiffJobs(redisJobspec(), redisJobspec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoDiff(t, diff)
+
+	// Canonicalizing a job doesn't change it.
+	canonical := redisJobspec()
+	canonical.Canonicalize()
+	diff, err = DiffJobs(canonical, redisJobspec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoDiff(t, diff)
+}
+
+func TestDiffJobsServerJob(t *testing.T) {
+	diff, err := DiffJobs(serverJob(t), redisJobspec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoDiff(t, diff)
+
+	job := redisJobspec()
+	job.TaskGroups[0].Tasks[0].SetConfig("image", "redis:7")
+	diff, err = DiffJobs(serverJob(t), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Type != diffTypeEdited {
+		t.Errorf("expected an edited job, got %s", diff.Type)
+	}
+	changes := QueryDiff(diff).Changes()
+	if len(changes) != 1 || changes[0].Path != "TaskGroup[cache].Task[redis].Config.image" {
+		t.Errorf("expected only the image to change:\n%s", stripColor(formatJobDiffFlat(diff)))
+	}
+}
+
+func TestDiffJobsObjectNames(t *testing.T) {
+	job := redisJobspec()
+	job.Constrain(api.NewConstraint("${attr.kernel.name}", "=", "linux"))
+	job.Constrain(api.NewConstraint("${attr.cpu.arch}", "=", "amd64"))
+	job.TaskGroups[0].Tasks[0].Services = []*api.Service{{Name: "redis", PortLabel: "db"}}
+
+	diff, err := DiffJobs(redisJobspec(), job)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, object := range diff.Objects {
+		names = append(names, object.Type+" "+object.Name)
+	}
+	if got := strings.Join(names, ", "); got != "Added Constraint, Added Constraint" {
+		t.Errorf("unexpected job objects: %s", got)
+	}
+
+	task := diff.TaskGroups[0].Tasks[0]
+	if len(task.Objects) != 1 || task.Objects[0].Name != "Service" {
+		t.Errorf("expected an added Service object:\n%s", stripColor(formatJobDiffFlat(diff)))
+	}
+}
+
+func TestDiffJobsNewJob(t *testing.T) {
+	diff, err := DiffJobs(nil, redisJobspec())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Type != diffTypeAdded {
+		t.Errorf("expected an added job, got %s", diff.Type)
+	}
+	if len(diff.TaskGroups) != 1 || diff.TaskGroups[0].Type != diffTypeAdded {
+		t.Errorf("expected an added task group")
+	}
+
+	if _, err := DiffJobs(redisJobspec(), nil); err == nil {
+		t.Errorf("expected an error diffing a nil job")
+	}
+}
+
+func TestDiffJobsZeroValues(t *testing.T) {
+	old := redisJobspec()
+	job := redisJobspec()
+	zero := 0
+	job.TaskGroups[0].Tasks[0].Resources.DiskMB = &zero
+
+	diff, err := DiffJobs(old, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNoDiff(t, diff)
+
+	// A change from zero is still reported.
+	one := 1
+	job.TaskGroups[0].Tasks[0].Resources.DiskMB = &one
+	diff, err = DiffJobs(old, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := QueryDiff(diff).Get("TaskGroup[cache].Task[redis].Resources.DiskMB"); c == nil || c.New != "1" {
+		t.Errorf("expected DiskMB to change:\n%s", stripColor(formatJobDiffFlat(diff)))
+	}
+}
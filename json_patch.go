@@ -0,0 +1,359 @@
+package nomaddiffprinter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// PatchOperation is a single RFC 6902 JSON Patch operation.
+type PatchOperation struct {
+	Op    string
+	Path  string
+	Value interface{}
+}
+
+// MarshalJSON encodes the operation, omitting the value of remove operations
+// only, since a null or zero value is meaningful for add and replace.
+func (o *PatchOperation) MarshalJSON() ([]byte, error) {
+	if o.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+	return json.Marshal(struct {
+		Op    string      `json:"op"`
+		Path  string      `json:"path"`
+		Value interface{} `json:"value"`
+	}{o.Op, o.Path, o.Value})
+}
+
+// diffObjectKeys maps the names the Nomad server and DiffJobs give objects in
+// a diff to the keys of the job's API JSON. It is the inverse of
+// diffObjectNames. Objects whose name is already the JSON key are not listed.
+var diffObjectKeys = invertNames(diffObjectNames)
+
+// invertNames swaps the keys and values of names.
+func invertNames(names map[string]string) map[string]string {
+	out := make(map[string]string, len(names))
+	for k, v := range names {
+		out[v] = k
+	}
+	return out
+}
+
+// JSONPatch converts a job diff into an RFC 6902 JSON Patch that turns the
+// API JSON of the registered job into that of the planned job. Both jobs are
+// needed because the diff identifies task groups and tasks by name while the
+// patch addresses them by index. A nil registered job produces a single
+// operation that adds the whole job.
+//
+// Fields are patched individually. Objects are patched as a whole, since
+// elements of lists such as constraints and services have no identity in the
+// diff. The value of every operation is taken from the canonicalized planned
+// job so that it has the type the JSON expects rather than the string form in
+// the diff.
+func JSONPatch(registered, job *api.Job, diff *api.JobDiff) ([]*PatchOperation, error) {
+	newDoc, err := jobDocument(job)
+	if err != nil {
+		return nil, err
+	}
+	if registered == nil {
+		return []*PatchOperation{{Op: "add", Path: "", Value: newDoc}}, nil
+	}
+	oldDoc, err := jobDocument(registered)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &jsonPatcher{old: oldDoc, new: newDoc, seen: make(map[string]bool)}
+	if err := p.patchJob(diff); err != nil {
+		return nil, err
+	}
+	return p.operations(), nil
+}
+
+// WriteJSONPatch writes the JSON Patch for the job diff to w. See JSONPatch.
+func WriteJSONPatch(w io.Writer, registered, job *api.Job, diff *api.JobDiff) error {
+	ops, err := JSONPatch(registered, job, diff)
+	if err != nil {
+		return err
+	}
+	if ops == nil {
+		ops = []*PatchOperation{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(ops)
+}
+
+// jobDocument returns the API JSON of the canonicalized job decoded into
+// generic maps and slices.
+func jobDocument(job *api.Job) (interface{}, error) {
+	if job == nil {
+		return nil, fmt.Errorf("must pass non-nil job")
+	}
+	job, err := canonicalizedCopy(job)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job: %v", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(buf, &doc); err != nil {
+		return nil, fmt.Errorf("error decoding job: %v", err)
+	}
+	return doc, nil
+}
+
+// jsonLocation is the location of a value in both the registered and planned
+// job documents. The indices of task groups and tasks may differ between the
+// two.
+type jsonLocation struct {
+	old, new []string
+}
+
+func (l jsonLocation) child(key string) jsonLocation {
+	return jsonLocation{old: appendToken(l.old, key), new: appendToken(l.new, key)}
+}
+
+func appendToken(tokens []string, token string) []string {
+	out := make([]string, len(tokens), len(tokens)+1)
+	copy(out, tokens)
+	return append(out, token)
+}
+
+// jsonPatcher accumulates the operations of a patch. Edits are addressed by
+// the indices of the registered job, so removals of task groups and tasks are
+// deferred until after them and applied from the highest index down, and
+// additions are appended last.
+type jsonPatcher struct {
+	old, new interface{}
+	seen     map[string]bool
+
+	edits      []*PatchOperation
+	taskOps    []*PatchOperation
+	groupRmIdx []int
+	groupAdds  []*PatchOperation
+}
+
+func (p *jsonPatcher) operations() []*PatchOperation {
+	ops := append(p.edits, p.taskOps...)
+	sort.Sort(sort.Reverse(sort.IntSlice(p.groupRmIdx)))
+	for _, i := range p.groupRmIdx {
+		ops = append(ops, &PatchOperation{Op: "remove", Path: jsonPointer([]string{"TaskGroups", strconv.Itoa(i)})})
+	}
+	return append(ops, p.groupAdds...)
+}
+
+func (p *jsonPatcher) patchJob(diff *api.JobDiff) error {
+	if diff == nil {
+		return nil
+	}
+	if err := p.patchFieldsAndObjects(jsonLocation{}, diff.Fields, diff.Objects); err != nil {
+		return err
+	}
+
+	for _, tg := range diff.TaskGroups {
+		oldIdx := namedIndex(p.old, []string{"TaskGroups"}, tg.Name)
+		newIdx := namedIndex(p.new, []string{"TaskGroups"}, tg.Name)
+		switch {
+		case oldIdx < 0 && newIdx < 0:
+			return fmt.Errorf("task group %q not found in either job", tg.Name)
+		case oldIdx < 0:
+			p.groupAdds = append(p.groupAdds, &PatchOperation{
+				Op:    "add",
+				Path:  "/TaskGroups/-",
+				Value: lookup(p.new, []string{"TaskGroups", strconv.Itoa(newIdx)}),
+			})
+		case newIdx < 0:
+			p.groupRmIdx = append(p.groupRmIdx, oldIdx)
+		default:
+			loc := jsonLocation{
+				old: []string{"TaskGroups", strconv.Itoa(oldIdx)},
+				new: []string{"TaskGroups", strconv.Itoa(newIdx)},
+			}
+			if err := p.patchTaskGroup(loc, tg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (p *jsonPatcher) patchTaskGroup(loc jsonLocation, tg *api.TaskGroupDiff) error {
+	if err := p.patchFieldsAndObjects(loc, tg.Fields, tg.Objects); err != nil {
+		return err
+	}
+
+	tasks := loc.child("Tasks")
+	var removed []int
+	var added []*PatchOperation
+	for _, task := range tg.Tasks {
+		oldIdx := namedIndex(p.old, tasks.old, task.Name)
+		newIdx := namedIndex(p.new, tasks.new, task.Name)
+		switch {
+		case oldIdx < 0 && newIdx < 0:
+			return fmt.Errorf("task %q not found in either job", task.Name)
+		case oldIdx < 0:
+			added = append(added, &PatchOperation{
+				Op:    "add",
+				Path:  jsonPointer(appendToken(tasks.old, "-")),
+				Value: lookup(p.new, appendToken(tasks.new, strconv.Itoa(newIdx))),
+			})
+		case newIdx < 0:
+			removed = append(removed, oldIdx)
+		default:
+			taskLoc := jsonLocation{
+				old: appendToken(tasks.old, strconv.Itoa(oldIdx)),
+				new: appendToken(tasks.new, strconv.Itoa(newIdx)),
+			}
+			if err := p.patchFieldsAndObjects(taskLoc, task.Fields, task.Objects); err != nil {
+				return err
+			}
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(removed)))
+	for _, i := range removed {
+		p.taskOps = append(p.taskOps, &PatchOperation{Op: "remove", Path: jsonPointer(appendToken(tasks.old, strconv.Itoa(i)))})
+	}
+	p.taskOps = append(p.taskOps, added...)
+	return nil
+}
+
+func (p *jsonPatcher) patchFieldsAndObjects(loc jsonLocation, fields []*api.FieldDiff, objects []*api.ObjectDiff) error {
+	for _, field := range fields {
+		if err := p.patchValue(loc, fieldTokens(field.Name)); err != nil {
+			return err
+		}
+	}
+	for _, object := range objects {
+		if err := p.patchValue(loc, []string{objectKey(object.Name)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// patchValue adds an operation for the value at the given tokens below loc.
+// The operation depends on where the value is present rather than on the diff
+// type, since the server reports an unset struct field as deleted while its
+// JSON key is still present as null.
+func (p *jsonPatcher) patchValue(loc jsonLocation, tokens []string) error {
+	at := loc
+	for _, t := range tokens {
+		at = at.child(t)
+	}
+
+	// A map entry can't be added to a map that is null in the registered
+	// job, so the whole map is replaced instead.
+	oldValue, inOld := lookupOK(p.old, at.old)
+	if !inOld && len(tokens) > 1 {
+		if parent, ok := lookupOK(p.old, at.old[:len(at.old)-1]); ok && parent == nil {
+			at = jsonLocation{old: at.old[:len(at.old)-1], new: at.new[:len(at.new)-1]}
+			oldValue, inOld = nil, true
+		}
+	}
+	newValue, inNew := lookupOK(p.new, at.new)
+
+	for i := len(loc.old) + 1; i <= len(at.old); i++ {
+		if p.seen[jsonPointer(at.old[:i])] {
+			return nil
+		}
+	}
+	path := jsonPointer(at.old)
+	p.seen[path] = true
+
+	switch {
+	case inOld && inNew:
+		if reflect.DeepEqual(oldValue, newValue) {
+			return nil
+		}
+		p.edits = append(p.edits, &PatchOperation{Op: "replace", Path: path, Value: newValue})
+	case inNew:
+		p.edits = append(p.edits, &PatchOperation{Op: "add", Path: path, Value: newValue})
+	case inOld:
+		p.edits = append(p.edits, &PatchOperation{Op: "remove", Path: path})
+	default:
+		return fmt.Errorf("no JSON value for diff path %q", strings.Join(tokens, "."))
+	}
+	return nil
+}
+
+// fieldTokens returns the JSON tokens of a field diff name. Map entries are
+// named Name[key] in a diff.
+func fieldTokens(name string) []string {
+	i := strings.Index(name, "[")
+	if i > 0 && strings.HasSuffix(name, "]") {
+		return []string{name[:i], name[i+1 : len(name)-1]}
+	}
+	return []string{name}
+}
+
+// objectKey returns the JSON key of an object diff name.
+func objectKey(name string) string {
+	if key, ok := diffObjectKeys[name]; ok {
+		return key
+	}
+	return name
+}
+
+// namedIndex returns the index of the element with the given Name in the list
+// at tokens, or -1.
+func namedIndex(doc interface{}, tokens []string, name string) int {
+	list, _ := lookup(doc, tokens).([]interface{})
+	for i, e := range list {
+		if m, ok := e.(map[string]interface{}); ok && m["Name"] == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func lookup(doc interface{}, tokens []string) interface{} {
+	v, _ := lookupOK(doc, tokens)
+	return v
+}
+
+// lookupOK returns the value at tokens and whether it is present.
+func lookupOK(doc interface{}, tokens []string) (interface{}, bool) {
+	v := doc
+	for _, t := range tokens {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			var ok bool
+			if v, ok = c[t]; !ok {
+				return nil, false
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(t)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// jsonPointer formats tokens as an RFC 6901 JSON Pointer.
+func jsonPointer(tokens []string) string {
+	var b strings.Builder
+	for _, t := range tokens {
+		b.WriteString("/")
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(t))
+	}
+	return b.String()
+}
@@ -0,0 +1,306 @@
+package nomaddiffprinter
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestFieldTokens(t *testing.T) {
+	cases := []struct {
+		name     string
+		expected []string
+	}{
+		{"Count", []string{"Count"}},
+		{"Meta[owner]", []string{"Meta", "owner"}},
+		{"Meta[a.b]", []string{"Meta", "a.b"}},
+		{"Meta[]", []string{"Meta", ""}},
+		{"[owner]", []string{"[owner]"}},
+		{"Meta[owner", []string{"Meta[owner"}},
+	}
+	for _, c := range cases {
+		if got := fieldTokens(c.name); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("fieldTokens(%q) = %q, expected %q", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestObjectKey(t *testing.T) {
+	cases := []struct {
+		name, expected string
+	}{
+		{"Constraint", "Constraints"},
+		{"Affinity", "Affinities"},
+		{"ScalingPolicy", "ScalingPolicies"},
+		{"VolumeMount", "VolumeMounts"},
+		{"Check", "Checks"},
+		{"Device", "Devices"},
+		{"Config", "Config"},
+		{"Resources", "Resources"},
+		{"Update", "Update"},
+	}
+	for _, c := range cases {
+		if got := objectKey(c.name); got != c.expected {
+			t.Errorf("objectKey(%q) = %q, expected %q", c.name, got, c.expected)
+		}
+	}
+}
+
+func TestObjectKeyInvertsObjectName(t *testing.T) {
+	for field := range diffObjectNames {
+		if got := objectKey(objectName(field)); got != field {
+			t.Errorf("objectKey(objectName(%q)) = %q", field, got)
+		}
+	}
+}
+
+func TestNamedIndex(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"TaskGroups": [
+			{"Name": "web", "Tasks": [{"Name": "app"}, {"Name": "sidecar"}]},
+			{"Name": "api"},
+			"invalid"
+		],
+		"Meta": null
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		tokens   []string
+		name     string
+		expected int
+	}{
+		{[]string{"TaskGroups"}, "web", 0},
+		{[]string{"TaskGroups"}, "api", 1},
+		{[]string{"TaskGroups"}, "worker", -1},
+		{[]string{"TaskGroups", "0", "Tasks"}, "sidecar", 1},
+		{[]string{"TaskGroups", "1", "Tasks"}, "app", -1},
+		{[]string{"Meta"}, "web", -1},
+		{[]string{"Missing"}, "web", -1},
+	}
+	for _, c := range cases {
+		if got := namedIndex(doc, c.tokens, c.name); got != c.expected {
+			t.Errorf("namedIndex(%q, %q) = %d, expected %d", c.tokens, c.name, got, c.expected)
+		}
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	cases := []struct {
+		tokens   []string
+		expected string
+	}{
+		{nil, ""},
+		{[]string{"TaskGroups", "0"}, "/TaskGroups/0"},
+		{[]string{"Meta", "a/b~c"}, "/Meta/a~1b~0c"},
+	}
+	for _, c := range cases {
+		if got := jsonPointer(c.tokens); got != c.expected {
+			t.Errorf("jsonPointer(%q) = %q, expected %q", c.tokens, got, c.expected)
+		}
+	}
+}
+
+// patchTestJobs returns a registered job and a planned job that removes and
+// adds task groups and tasks on either side of an edited one.
+func patchTestJobs() (registered, job *api.Job) {
+	newJob := func(groups ...*api.TaskGroup) *api.Job {
+		j := api.NewServiceJob("example", "example", "global", 50)
+		j.AddDatacenter("dc1")
+		for _, tg := range groups {
+			j.AddTaskGroup(tg)
+		}
+		return j
+	}
+	newTask := func(name, image string) *api.Task {
+		return api.NewTask(name, "docker").SetConfig("image", image)
+	}
+
+	registered = newJob(
+		api.NewTaskGroup("api", 1).AddTask(newTask("server", "api:1")),
+		api.NewTaskGroup("cache", 1).AddTask(newTask("redis", "redis:6")),
+		api.NewTaskGroup("web", 1).
+			AddTask(newTask("logger", "fluentd:1")).
+			AddTask(newTask("sidecar", "envoy:1")).
+			AddTask(newTask("app", "app:1")),
+	)
+
+	job = newJob(
+		api.NewTaskGroup("web", 2).
+			AddTask(newTask("app", "app:2").SetMeta("owner", "web-team")).
+			AddTask(newTask("metrics", "prom:1")),
+		api.NewTaskGroup("worker", 1).AddTask(newTask("worker", "worker:1")),
+	)
+	job.Constrain(api.NewConstraint("${attr.kernel.name}", "=", "linux"))
+	job.SetMeta("team", "platform")
+	return registered, job
+}
+
+func TestJSONPatchOrdering(t *testing.T) {
+	registered, job := patchTestJobs()
+	diff, err := DiffJobs(registered, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := JSONPatch(registered, job, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var paths []string
+	for _, op := range ops {
+		paths = append(paths, op.Op+" "+op.Path)
+	}
+	index := func(s string) int {
+		for i, p := range paths {
+			if p == s {
+				return i
+			}
+		}
+		t.Fatalf("missing operation %q in:\n%s", s, strings.Join(paths, "\n"))
+		return -1
+	}
+
+	// Edits use the indices of the registered job, so they come before any
+	// removal, removals go from the highest index down, and additions are
+	// appended last.
+	edit := index("replace /TaskGroups/2/Count")
+	removeSidecar := index("remove /TaskGroups/2/Tasks/1")
+	removeLogger := index("remove /TaskGroups/2/Tasks/0")
+	addMetrics := index("add /TaskGroups/2/Tasks/-")
+	removeCache := index("remove /TaskGroups/1")
+	removeAPI := index("remove /TaskGroups/0")
+	addWorker := index("add /TaskGroups/-")
+
+	order := []int{edit, removeSidecar, removeLogger, addMetrics, removeCache, removeAPI, addWorker}
+	for i := 1; i < len(order); i++ {
+		if order[i-1] > order[i] {
+			t.Fatalf("operations are out of order:\n%s", strings.Join(paths, "\n"))
+		}
+	}
+}
+
+func TestJSONPatchApply(t *testing.T) {
+	registered, job := patchTestJobs()
+	diff, err := DiffJobs(registered, job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := JSONPatch(registered, job, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Round trip the patch through JSON as a consumer would.
+	buf, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []struct {
+		Op    string
+		Path  string
+		Value interface{}
+	}
+	if err := json.Unmarshal(buf, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := jobDocument(registered)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, op := range decoded {
+		if doc, err = applyPatchOperation(doc, op.Op, op.Path, op.Value); err != nil {
+			t.Fatalf("error applying %s %s: %v", op.Op, op.Path, err)
+		}
+	}
+
+	expected, err := jobDocument(job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(doc, expected) {
+		got, _ := json.MarshalIndent(doc, "", "  ")
+		want, _ := json.MarshalIndent(expected, "", "  ")
+		t.Errorf("patched job doesn't match the planned job:\n%s\nexpected:\n%s", got, want)
+	}
+}
+
+func TestJSONPatchNewJob(t *testing.T) {
+	_, job := patchTestJobs()
+	ops, err := JSONPatch(nil, job, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != 1 || ops[0].Op != "add" || ops[0].Path != "" {
+		t.Errorf("expected a single operation adding the job, got %+v", ops)
+	}
+}
+
+// applyPatchOperation applies an add, replace or remove operation to doc,
+// which is enough of RFC 6902 to check the patches produced by JSONPatch.
+func applyPatchOperation(doc interface{}, op, path string, value interface{}) (interface{}, error) {
+	if path == "" {
+		if op == "remove" {
+			return nil, nil
+		}
+		return value, nil
+	}
+
+	var tokens []string
+	for _, t := range strings.Split(path, "/")[1:] {
+		tokens = append(tokens, strings.NewReplacer("~1", "/", "~0", "~").Replace(t))
+	}
+	parentTokens, last := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+	parent, ok := lookupOK(doc, parentTokens)
+	if !ok {
+		return nil, fmt.Errorf("parent of %q not found", path)
+	}
+
+	switch c := parent.(type) {
+	case map[string]interface{}:
+		if _, exists := c[last]; op != "add" && !exists {
+			return nil, fmt.Errorf("%q not found", path)
+		}
+		if op == "remove" {
+			delete(c, last)
+		} else {
+			c[last] = value
+		}
+		return doc, nil
+
+	case []interface{}:
+		var updated []interface{}
+		switch {
+		case op == "add" && last == "-":
+			updated = append(c, value)
+		default:
+			i, err := strconv.Atoi(last)
+			if err != nil || i < 0 || i >= len(c) {
+				return nil, fmt.Errorf("invalid index in %q", path)
+			}
+			switch op {
+			case "remove":
+				updated = append(append([]interface{}{}, c[:i]...), c[i+1:]...)
+			case "replace":
+				c[i] = value
+				return doc, nil
+			default:
+				updated = append(append(append([]interface{}{}, c[:i]...), value), c[i:]...)
+			}
+		}
+		// Slices can't be modified in place, so the list is set again in its
+		// parent.
+		return applyPatchOperation(doc, "replace", jsonPointer(parentTokens), updated)
+
+	default:
+		return nil, fmt.Errorf("parent of %q is not a container", path)
+	}
+}
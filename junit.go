@@ -0,0 +1,140 @@
+package nomaddiffprinter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// TaskGroupRule is a deploy-readiness check run against every task group of a
+// plan. Returning an error marks the group's JUnit test case as failed.
+type TaskGroupRule func(group string, updates *api.DesiredUpdates, diff *api.TaskGroupDiff) error
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	TestCases []junitTestCase `xml:"testcase"`
+	SystemErr *junitOutput    `xml:"system-err,omitempty"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failures  []junitResult `xml:"failure,omitempty"`
+	Skipped   *junitResult  `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Body    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+// WriteJUnitReport renders the result of a plan as JUnit XML with a test case
+// per task group. A test case fails if the group has failed allocations or if
+// any of the rules returns an error, and is skipped if the group has no
+// changes. Plan warnings are reported in the suite's system-err.
+func WriteJUnitReport(w io.Writer, job *api.Job, resp *api.JobPlanResponse, rules ...TaskGroupRule) error {
+	var jobID string
+	if job.ID != nil {
+		jobID = *job.ID
+	}
+
+	suite := junitTestSuite{Name: jobID}
+	if resp.Warnings != "" {
+		suite.SystemErr = &junitOutput{Text: resp.Warnings}
+	}
+	for _, tg := range planTaskGroups(resp) {
+		tc := junitTaskGroupCase(jobID, tg, resp, rules)
+		if len(tc.Failures) > 0 {
+			suite.Failures++
+		} else if tc.Skipped != nil {
+			suite.Skipped++
+		}
+		suite.TestCases = append(suite.TestCases, tc)
+	}
+	suite.Tests = len(suite.TestCases)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitTaskGroupCase builds the test case of a single task group.
+func junitTaskGroupCase(jobID, tg string, resp *api.JobPlanResponse, rules []TaskGroupRule) junitTestCase {
+	tc := junitTestCase{Name: tg, ClassName: jobID}
+
+	var updates *api.DesiredUpdates
+	if resp.Annotations != nil {
+		updates = resp.Annotations.DesiredTGUpdates[tg]
+	}
+	if updates == nil {
+		updates = &api.DesiredUpdates{}
+	}
+	var diff *api.TaskGroupDiff
+	if resp.Diff != nil {
+		for _, d := range resp.Diff.TaskGroups {
+			if d.Name == tg {
+				diff = d
+			}
+		}
+	}
+
+	if metrics, ok := resp.FailedTGAllocs[tg]; ok {
+		noun := "allocation"
+		if metrics.CoalescedFailures > 0 {
+			noun += "s"
+		}
+		tc.Failures = append(tc.Failures, junitResult{
+			Message: fmt.Sprintf("failed to place %d %s", metrics.CoalescedFailures+1, noun),
+			Body:    stripColor(formatAllocMetrics(metrics, false, "")),
+		})
+	}
+	for _, rule := range rules {
+		if err := rule(tg, updates, diff); err != nil {
+			tc.Failures = append(tc.Failures, junitResult{Message: err.Error()})
+		}
+	}
+
+	if len(tc.Failures) == 0 && (diff == nil || diff.Type == diffTypeNone) && !hasUpdates(updates) {
+		tc.Skipped = &junitResult{Message: "no changes"}
+	}
+
+	out := formatKV([]string{
+		fmt.Sprintf("Place|%d", updates.Place),
+		fmt.Sprintf("Stop|%d", updates.Stop),
+		fmt.Sprintf("Migrate|%d", updates.Migrate),
+		fmt.Sprintf("In-place update|%d", updates.InPlaceUpdate),
+		fmt.Sprintf("Destructive update|%d", updates.DestructiveUpdate),
+		fmt.Sprintf("Canary|%d", updates.Canary),
+		fmt.Sprintf("Preemptions|%d", updates.Preemptions),
+		fmt.Sprintf("Ignore|%d", updates.Ignore),
+	})
+	if diff != nil {
+		out += "\n\n" + strings.TrimSpace(stripColor(formatTaskGroupDiff(diff, 0, false, nil)))
+	}
+	tc.SystemOut = &junitOutput{Text: out}
+
+	return tc
+}
@@ -0,0 +1,113 @@
+package nomaddiffprinter
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// Layout selects how the fields of a diff are printed.
+type Layout int
+
+const (
+	// LayoutInline prints each field on a single line as `old => new`.
+	LayoutInline Layout = iota
+
+	// LayoutSideBySide prints the old and new values of each field in two
+	// aligned columns.
+	LayoutSideBySide
+
+	// LayoutFlat prints each changed field on its own line prefixed by its
+	// fully qualified path, sorted by path.
+	LayoutFlat
+)
+
+// Overflow selects how values that don't fit in the output width are printed.
+type Overflow int
+
+const (
+	// OverflowNone prints values on a single line regardless of their length.
+	OverflowNone Overflow = iota
+
+	// OverflowWrap wraps long values onto the following lines, indented to
+	// the start of the value.
+	OverflowWrap
+
+	// OverflowTruncate cuts long values with an ellipsis and notes how many
+	// characters were left out.
+	OverflowTruncate
+)
+
+const (
+	// defaultWidth is the output width used when it can't be determined from
+	// the terminal.
+	defaultWidth = 120
+)
+
+// PlanOptions configures how a job is planned.
+type PlanOptions struct {
+	// Validate validates the job with the server before it is planned. Any
+	// validation errors and warnings are printed, and an invalid job is not
+	// planned.
+	Validate bool
+
+	// PolicyOverride overrides soft-mandatory Sentinel policies that the job
+	// fails. Their failures are printed rather than failing the plan.
+	PolicyOverride bool
+
+	// WarningsAsErrors are the categories of warnings that fail the plan. If
+	// the plan returns a warning of one of them, the plan is still printed
+	// and returned along with a *WarningError.
+	WarningsAsErrors []WarningCategory
+}
+
+// PrintOptions configures how a plan is printed.
+type PrintOptions struct {
+	// Layout selects how field diffs are printed.
+	Layout Layout
+
+	// Width is the width of the output in columns. If zero, the width of the
+	// terminal is used.
+	Width int
+
+	// Overflow selects how values and table rows longer than Width are
+	// printed.
+	Overflow Overflow
+
+	// VerboseDryRun lists every evaluation the plan would create after the
+	// scheduler dry-run.
+	VerboseDryRun bool
+}
+
+// width returns the configured output width, falling back to the width of the
+// terminal.
+func (o *PrintOptions) width() int {
+	if o != nil && o.Width > 0 {
+		return o.Width
+	}
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	return defaultWidth
+}
+
+// layout returns the configured layout.
+func (o *PrintOptions) layout() Layout {
+	if o == nil {
+		return LayoutInline
+	}
+	return o.Layout
+}
+
+// overflow returns the configured overflow mode.
+func (o *PrintOptions) overflow() Overflow {
+	if o == nil {
+		return OverflowNone
+	}
+	return o.Overflow
+}
+
+// verboseDryRun returns whether the created evaluations should be listed.
+func (o *PrintOptions) verboseDryRun() bool {
+	return o != nil && o.VerboseDryRun
+}
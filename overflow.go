@@ -0,0 +1,158 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// minValueWidth is the narrowest a value is wrapped or truncated to, so
+	// that deeply nested values remain readable on narrow terminals.
+	minValueWidth = 20
+
+	// editSeparator separates the old and new values of an edited field.
+	editSeparator = " => "
+)
+
+// fitValue fits a value that starts at column valueStart into the output
+// width. Depending on the configured overflow mode the value is wrapped onto
+// lines indented to valueStart, truncated, or left untouched.
+func fitValue(value string, valueStart int, printOpts *PrintOptions) string {
+	mode := printOpts.overflow()
+	if mode == OverflowNone {
+		return value
+	}
+
+	width := valueWidth(valueStart, printOpts)
+	if mode == OverflowTruncate {
+		return truncateWidth(value, width)
+	}
+	return strings.Join(splitWidth(value, width), "\n"+strings.Repeat(" ", valueStart))
+}
+
+// fitEdit is like fitValue for the old and new values of an edited field.
+// When truncating, each value is truncated separately so that the new value
+// is never cut off by a long old value.
+func fitEdit(old, new string, valueStart int, printOpts *PrintOptions) string {
+	value := old + editSeparator + new
+	if printOpts.overflow() != OverflowTruncate {
+		return fitValue(value, valueStart, printOpts)
+	}
+
+	width := valueWidth(valueStart, printOpts)
+	if displayWidth(value) <= width {
+		return value
+	}
+
+	// Split the width evenly, giving the room a short value doesn't need to
+	// the other one.
+	width -= len(editSeparator)
+	oldWidth := width / 2
+	newWidth := width - oldWidth
+	if w := displayWidth(old); w < oldWidth {
+		newWidth += oldWidth - w
+		oldWidth = w
+	} else if w := displayWidth(new); w < newWidth {
+		oldWidth += newWidth - w
+		newWidth = w
+	}
+	return truncateWidth(old, oldWidth) + editSeparator + truncateWidth(new, newWidth)
+}
+
+// valueWidth returns the width available to a value that starts at column
+// valueStart.
+func valueWidth(valueStart int, printOpts *PrintOptions) int {
+	width := printOpts.width() - valueStart
+	if width < minValueWidth {
+		width = minValueWidth
+	}
+	return width
+}
+
+// fitTable fits every row of a columnized table into the output width. Wrapped
+// rows are continued at the start of the last column.
+func fitTable(table string, printOpts *PrintOptions) string {
+	mode := printOpts.overflow()
+	if mode == OverflowNone {
+		return table
+	}
+
+	rows := strings.Split(table, "\n")
+	lastColumn := lastColumnStart(rows[0])
+	width := printOpts.width()
+	for i, row := range rows {
+		if displayWidth(row) <= width {
+			continue
+		}
+		if mode == OverflowTruncate {
+			rows[i] = truncateWidth(row, width)
+			continue
+		}
+
+		head, tail := cutWidth(row, lastColumn)
+		valueWidth := width - lastColumn
+		if valueWidth < minValueWidth {
+			valueWidth = minValueWidth
+		}
+		rows[i] = head + strings.Join(splitWidth(tail, valueWidth), "\n"+strings.Repeat(" ", lastColumn))
+	}
+	return strings.Join(rows, "\n")
+}
+
+// lastColumnStart returns the column at which the last column of a columnized
+// header row starts.
+func lastColumnStart(header string) int {
+	if i := strings.LastIndex(strings.TrimRight(header, " "), "  "); i >= 0 {
+		return displayWidth(header[:i+2])
+	}
+	return 0
+}
+
+// truncateWidth cuts s to at most width columns, replacing the end with an
+// ellipsis and the number of characters that were left out. The note is
+// dropped when there is no room for it, leaving only the ellipsis. Color
+// annotations don't count as characters and those cut off are kept, so that
+// colors are still reset.
+func truncateWidth(s string, width int) string {
+	if displayWidth(s) <= width {
+		return s
+	}
+
+	// The length of the note depends on the number of characters removed, so
+	// it is computed against the worst case first.
+	total := len(graphemes(stripColor(s)))
+	note := fmt.Sprintf("… (%d more chars)", total)
+	keep := width - displayWidth(note)
+	if keep < 1 {
+		keep = width - displayWidth("…")
+		if keep < 0 {
+			return ""
+		}
+		head, tail := cutWidth(s, keep)
+		return head + colorTags(tail) + "…"
+	}
+	head, tail := cutWidth(s, keep)
+	return head + colorTags(tail) + fmt.Sprintf("… (%d more chars)", total-len(graphemes(stripColor(head))))
+}
+
+// splitWidth splits s into lines of at most width columns, breaking after a
+// space when there is one in the second half of the line.
+func splitWidth(s string, width int) []string {
+	if s == "" {
+		return []string{""}
+	}
+
+	var lines []string
+	for displayWidth(s) > width {
+		head, tail := cutWidth(s, width)
+		if head == "" {
+			// A single character wider than the line
+			head, tail = graphemes(s)[0], s[len(graphemes(s)[0]):]
+		} else if i := strings.LastIndex(head, " "); i >= 0 && displayWidth(head[:i]) > width/2 {
+			head, tail = head[:i+1], head[i+1:]+tail
+		}
+		lines = append(lines, strings.TrimRight(head, " "))
+		s = tail
+	}
+	return append(lines, s)
+}
@@ -0,0 +1,148 @@
+package nomaddiffprinter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestTruncateWidth(t *testing.T) {
+	value := `"registry.example.com/team/service:1.2.3-rc1"`
+	for width := 0; width <= displayWidth(value)+1; width++ {
+		got := truncateWidth(value, width)
+		if w := displayWidth(got); w > width {
+			t.Errorf("truncateWidth(%d) = %q is %d columns wide", width, got, w)
+		}
+	}
+
+	if got := truncateWidth(value, 30); !strings.Contains(got, "more chars)") {
+		t.Errorf("truncateWidth(30) = %q, expected a note", got)
+	}
+	if got := truncateWidth(value, 8); got != `"regist…` {
+		t.Errorf("truncateWidth(8) = %q, expected only an ellipsis", got)
+	}
+	if got := truncateWidth("日本語の文字列", 5); displayWidth(got) > 5 {
+		t.Errorf("truncateWidth of wide characters = %q exceeds the width", got)
+	}
+}
+
+func TestTruncateWidthColor(t *testing.T) {
+	got := truncateWidth("web  [green]no changes at all today[reset]", 24)
+	if expected := "web  [green]no[reset]… (21 more chars)"; got != expected {
+		t.Errorf("truncateWidth = %q, expected %q", got, expected)
+	}
+}
+
+func TestFitTableColor(t *testing.T) {
+	results := []*PlanResult{{
+		Job:      testJob("example", "default"),
+		Response: &api.JobPlanResponse{Diff: &api.JobDiff{Type: diffTypeNone}, Annotations: &api.PlanAnnotations{}},
+	}}
+	table := formatPlanSummary(results)
+
+	for _, mode := range []Overflow{OverflowTruncate, OverflowWrap} {
+		out := fitTable(table, &PrintOptions{Width: 34, Overflow: mode})
+		if stripped := stripColor(out); strings.ContainsAny(stripped, "[]") {
+			t.Errorf("mode %d: color annotation was cut:\n%s", mode, stripped)
+		}
+		if !strings.HasSuffix(colorTags(out), "[reset]") {
+			t.Errorf("mode %d: the color of the last row isn't reset: %q", mode, out)
+		}
+	}
+
+	out := fitTable(table, &PrintOptions{Width: 34, Overflow: OverflowTruncate})
+	for _, line := range strings.Split(out, "\n") {
+		if w := displayWidth(line); w > 34 {
+			t.Errorf("line is %d columns wide: %q", w, line)
+		}
+	}
+}
+
+func TestFormatFieldDiffSideBySideNarrow(t *testing.T) {
+	diff := &api.FieldDiff{
+		Type: "Edited",
+		Name: "image",
+		Old:  "registry.example.com/team/service:1.2.3-rc1",
+		New:  "registry.example.com/team/service:1.2.4-rc1",
+	}
+
+	for _, width := range []int{20, 40, 60, 80} {
+		printOpts := &PrintOptions{Width: width, Overflow: OverflowTruncate, Layout: LayoutSideBySide}
+		out := stripColor(formatFieldDiffSideBySide(diff, 4, len(diff.Name), width, printOpts))
+		if n := strings.Count(out, "\n"); n != 1 {
+			t.Errorf("width %d: expected a single line, got %d:\n%s", width, n, out)
+		}
+		if !strings.Contains(out, sideBySideSeparator) {
+			t.Errorf("width %d: missing separator:\n%s", width, out)
+		}
+	}
+}
+
+func TestFormatJobDiffSideBySideTruncate(t *testing.T) {
+	job := &api.JobDiff{
+		Type: "Edited",
+		ID:   "example",
+		TaskGroups: []*api.TaskGroupDiff{{
+			Type: "Edited",
+			Name: "web",
+			Tasks: []*api.TaskDiff{{
+				Type: "Edited",
+				Name: "app",
+				Objects: []*api.ObjectDiff{{
+					Type: "Edited",
+					Name: "Config",
+					Fields: []*api.FieldDiff{{
+						Type: "Edited",
+						Name: "image",
+						Old:  "registry.example.com/team/service:1.2.3",
+						New:  "registry.example.com/team/service:1.2.4-rc1",
+					}},
+				}},
+			}},
+		}},
+	}
+
+	printOpts := &PrintOptions{Width: 60, Overflow: OverflowTruncate, Layout: LayoutSideBySide}
+	out := formatJobDiffSideBySide(job, false, printOpts)
+	if !strings.Contains(stripColor(out), "image:") {
+		t.Fatalf("missing field in output:\n%s", out)
+	}
+}
+
+func TestFormatFieldDiffTruncateEdit(t *testing.T) {
+	diff := &api.FieldDiff{
+		Type: "Edited",
+		Name: "image",
+		Old:  strings.Repeat("a", 80),
+		New:  strings.Repeat("b", 80),
+	}
+	printOpts := &PrintOptions{Width: 80, Overflow: OverflowTruncate}
+	out := stripColor(formatFieldDiff(diff, 2, 1, 0, printOpts))
+
+	if w := displayWidth(out); w > 80 {
+		t.Errorf("output is %d columns wide:\n%s", w, out)
+	}
+	parts := strings.Split(out, editSeparator)
+	if len(parts) != 2 {
+		t.Fatalf("expected old and new values separated by %q:\n%s", editSeparator, out)
+	}
+	for i, part := range parts {
+		if !strings.Contains(part, "more chars)") {
+			t.Errorf("value %d is missing its truncation note: %q", i, part)
+		}
+	}
+	if !strings.Contains(parts[1], `"bbb`) {
+		t.Errorf("new value was lost: %q", parts[1])
+	}
+
+	// A short old value leaves more room for the new one.
+	diff.Old = "a"
+	out = stripColor(formatFieldDiff(diff, 2, 1, 0, printOpts))
+	if !strings.HasPrefix(strings.TrimSpace(out), `+/-  image: "a" => "bbb`) {
+		t.Errorf("unexpected output: %q", out)
+	}
+	if w := displayWidth(out); w > 80 {
+		t.Errorf("output is %d columns wide:\n%s", w, out)
+	}
+}
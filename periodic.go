@@ -0,0 +1,326 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// periodicLaunchPreviewCount is the number of upcoming launches of a
+	// periodic job listed in the dry-run.
+	periodicLaunchPreviewCount = 5
+)
+
+var (
+	// cronPredefined are the descriptions of the predefined cron specs.
+	cronPredefined = map[string]string{
+		"@yearly":   "at 00:00 on January 1st",
+		"@annually": "at 00:00 on January 1st",
+		"@monthly":  "at 00:00 on the first day of every month",
+		"@weekly":   "at 00:00 every Sunday",
+		"@daily":    "at 00:00 every day",
+		"@midnight": "at 00:00 every day",
+		"@hourly":   "at the start of every hour",
+	}
+
+	cronDays   = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+	cronMonths = []string{"", "January", "February", "March", "April", "May", "June",
+		"July", "August", "September", "October", "November", "December"}
+)
+
+// formatPeriodicSchedule produces a string previewing the schedule of a
+// periodic job: a description of its cron spec, its next launches in the time
+// zone of the job and in local time, whether overlapping launches are
+// prohibited and, if the spec changed in the diff, the launches before and
+// after the change.
+func formatPeriodicSchedule(job *api.Job, diff *api.JobDiff, now time.Time) string {
+	p := job.Periodic
+	if p == nil || p.Spec == nil || (p.SpecType != nil && *p.SpecType != api.PeriodicSpecCron) {
+		return ""
+	}
+	loc, err := p.GetLocation()
+	if err != nil {
+		return ""
+	}
+
+	var out string
+	if oldSpec, changed := periodicSpecChange(diff); changed && oldSpec != "" {
+		out += fmt.Sprintf("[light_yellow]- Schedule changed from %s to %s.[reset]\n",
+			formatCronSpec(oldSpec), formatCronSpec(*p.Spec))
+		old := *p
+		old.Spec = &oldSpec
+		out += formatLaunchComparison(&old, p, loc, now)
+	} else {
+		out += fmt.Sprintf("[green]- Schedule: %s in %s.[reset]\n", formatCronSpec(*p.Spec), loc)
+		launches, err := nextLaunches(p, loc, now, periodicLaunchPreviewCount)
+		if err != nil {
+			return out + fmt.Sprintf("[yellow]- Invalid schedule: %v[reset]", err)
+		}
+		rows := []string{fmt.Sprintf("Launch (%s)|Local (%s)", loc, now.In(time.Local).Format("MST"))}
+		for _, t := range launches {
+			rows = append(rows, fmt.Sprintf("%s|%s", formatTime(t), formatTime(t.In(time.Local))))
+		}
+		out += indent(formatList(rows), "    ") + "\n"
+	}
+
+	if p.ProhibitOverlap != nil && *p.ProhibitOverlap {
+		out += "[yellow]- Overlap is prohibited: a launch is skipped while the previous one is still running.[reset]\n"
+	}
+	return strings.TrimSuffix(out, "\n")
+}
+
+// formatLaunchComparison produces a table of the next launches of the job
+// before and after its spec changed.
+func formatLaunchComparison(old, new *api.PeriodicConfig, loc *time.Location, now time.Time) string {
+	before, err := nextLaunches(old, loc, now, periodicLaunchPreviewCount)
+	if err != nil {
+		before = nil
+	}
+	after, err := nextLaunches(new, loc, now, periodicLaunchPreviewCount)
+	if err != nil {
+		return fmt.Sprintf("[yellow]- Invalid schedule: %v[reset]\n", err)
+	}
+
+	rows := []string{fmt.Sprintf("Before (%s)|After (%s)|After (%s)", loc, loc, now.In(time.Local).Format("MST"))}
+	for i := 0; i < periodicLaunchPreviewCount; i++ {
+		var b, a, l string
+		if i < len(before) {
+			b = formatTime(before[i])
+		}
+		if i < len(after) {
+			a = formatTime(after[i])
+			l = formatTime(after[i].In(time.Local))
+		}
+		rows = append(rows, fmt.Sprintf("%s|%s|%s", b, a, l))
+	}
+	return indent(formatList(rows), "    ") + "\n"
+}
+
+// nextLaunches returns up to n launch times of the periodic config after now
+// in the given time zone.
+func nextLaunches(p *api.PeriodicConfig, loc *time.Location, now time.Time, n int) ([]time.Time, error) {
+	// Next dereferences the spec type, which is only set once the job is
+	// canonicalized.
+	if p.SpecType == nil {
+		specType := api.PeriodicSpecCron
+		canonical := *p
+		canonical.SpecType = &specType
+		p = &canonical
+	}
+
+	var out []time.Time
+	t := now.In(loc)
+	for len(out) < n {
+		next, err := p.Next(t)
+		if err != nil {
+			return nil, err
+		}
+		if next.IsZero() {
+			break
+		}
+		out = append(out, next)
+		t = next
+	}
+	return out, nil
+}
+
+// periodicSpecChange returns the old spec of the job if the diff changed it.
+func periodicSpecChange(diff *api.JobDiff) (string, bool) {
+	if diff == nil {
+		return "", false
+	}
+	for _, object := range diff.Objects {
+		if object.Name != "Periodic" {
+			continue
+		}
+		for _, field := range object.Fields {
+			if field.Name == "Spec" && field.Type != diffTypeNone {
+				return field.Old, true
+			}
+		}
+	}
+	return "", false
+}
+
+// formatCronSpec formats a cron spec along with its description.
+func formatCronSpec(spec string) string {
+	if desc := describeCron(spec); desc != "" {
+		return fmt.Sprintf("%q (%s)", spec, desc)
+	}
+	return fmt.Sprintf("%q", spec)
+}
+
+// describeCron translates a cron spec into English, for example "0 2 * * 1-5"
+// into "at 02:00 on Monday through Friday". An empty string is returned for
+// specs using syntax it doesn't understand.
+func describeCron(spec string) string {
+	spec = strings.TrimSpace(spec)
+	if desc, ok := cronPredefined[strings.ToLower(spec)]; ok {
+		return desc
+	}
+
+	// Specs may have a leading seconds field and a trailing year field.
+	fields := strings.Fields(spec)
+	var second, year string
+	switch len(fields) {
+	case 5:
+	case 6:
+		year = fields[5]
+	case 7:
+		second, year = fields[0], fields[6]
+		fields = fields[1:]
+	default:
+		return ""
+	}
+	if second != "" && second != "0" {
+		return ""
+	}
+	minute, hour, dom, month, dow := fields[0], fields[1], fields[2], fields[3], fields[4]
+
+	var parts []string
+	minuteNum, minuteIsNum := cronNumber(minute, nil)
+	hourNum, hourIsNum := cronNumber(hour, nil)
+	switch {
+	case minuteIsNum && hourIsNum:
+		parts = append(parts, fmt.Sprintf("at %02d:%02d", hourNum, minuteNum))
+	case minuteIsNum && hour == "*":
+		parts = append(parts, fmt.Sprintf("at minute %d of every hour", minuteNum))
+	case minute == "*" && hour == "*":
+		parts = append(parts, "every minute")
+	case strings.HasPrefix(minute, "*/") && hour == "*":
+		parts = append(parts, fmt.Sprintf("every %s minutes", strings.TrimPrefix(minute, "*/")))
+	default:
+		m, ok := describeCronField(minute, "minute", nil)
+		if !ok {
+			return ""
+		}
+		h, ok := describeCronField(hour, "hour", nil)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, fmt.Sprintf("at %s past %s", m, h))
+	}
+
+	if dom != "*" && dom != "?" {
+		d, ok := describeCronField(dom, "day-of-month", nil)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, "on "+d)
+	}
+	if month != "*" {
+		m, ok := describeCronField(month, "month", cronMonths)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, "in "+m)
+	}
+	if dow != "*" && dow != "?" {
+		d, ok := describeCronField(dow, "day-of-week", cronDays)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, "on "+d)
+	}
+	if year != "" && year != "*" {
+		y, ok := describeCronField(year, "year", nil)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, "in "+y)
+	}
+	return strings.Join(parts, " ")
+}
+
+// describeCronField describes a single field of a cron spec. Names are used
+// for the values of fields such as months and days of the week.
+func describeCronField(field, unit string, names []string) (string, bool) {
+	if field == "*" {
+		return "every " + unit, true
+	}
+	if strings.HasPrefix(field, "*/") {
+		return fmt.Sprintf("every %s %s", ordinal(strings.TrimPrefix(field, "*/")), unit), true
+	}
+
+	var items []string
+	for _, item := range strings.Split(field, ",") {
+		if i := strings.Index(item, "-"); i > 0 {
+			from, ok := cronValue(item[:i], names)
+			if !ok {
+				return "", false
+			}
+			to, ok := cronValue(item[i+1:], names)
+			if !ok {
+				return "", false
+			}
+			items = append(items, fmt.Sprintf("%s through %s", from, to))
+			continue
+		}
+		v, ok := cronValue(item, names)
+		if !ok {
+			return "", false
+		}
+		items = append(items, v)
+	}
+
+	desc := strings.Join(items, ", ")
+	if names == nil {
+		desc = unit + " " + desc
+	}
+	return desc, true
+}
+
+// cronValue describes a single value of a cron field, using its name if the
+// field has names.
+func cronValue(v string, names []string) (string, bool) {
+	if n, ok := cronNumber(v, names); ok {
+		if names != nil {
+			if n == 7 && len(names) == 7 {
+				n = 0
+			}
+			if n >= 0 && n < len(names) && names[n] != "" {
+				return names[n], true
+			}
+			return "", false
+		}
+		return strconv.Itoa(n), true
+	}
+	return "", false
+}
+
+// cronNumber parses a numeric or named value of a cron field.
+func cronNumber(v string, names []string) (int, bool) {
+	if n, err := strconv.Atoi(v); err == nil {
+		return n, true
+	}
+	for i, name := range names {
+		if name != "" && len(v) == 3 && strings.EqualFold(v, name[:3]) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// ordinal formats a number as an ordinal such as 2nd. Other strings are
+// returned unchanged.
+func ordinal(s string) string {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return s
+	}
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%d%s", n, suffix)
+}
@@ -0,0 +1,62 @@
+package nomaddiffprinter
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestFormatPeriodicScheduleNoSpecType(t *testing.T) {
+	spec := "0 2 * * *"
+	job := &api.Job{Periodic: &api.PeriodicConfig{Spec: &spec}}
+	now := time.Date(2021, 8, 27, 12, 0, 0, 0, time.UTC)
+
+	out := formatPeriodicSchedule(job, nil, now)
+	if !strings.Contains(out, "at 02:00") {
+		t.Errorf("expected the schedule to be described:\n%s", out)
+	}
+	if !strings.Contains(out, "2021-08-28") {
+		t.Errorf("expected the next launch to be listed:\n%s", out)
+	}
+	if job.Periodic.SpecType != nil {
+		t.Errorf("the periodic config of the job was modified")
+	}
+}
+
+func TestFormatPeriodicScheduleChangedNoSpecType(t *testing.T) {
+	spec := "0 3 * * *"
+	job := &api.Job{Periodic: &api.PeriodicConfig{Spec: &spec}}
+	diff := &api.JobDiff{Objects: []*api.ObjectDiff{{
+		Type: "Edited",
+		Name: "Periodic",
+		Fields: []*api.FieldDiff{{
+			Type: "Edited",
+			Name: "Spec",
+			Old:  "0 2 * * *",
+			New:  spec,
+		}},
+	}}}
+	now := time.Date(2021, 8, 27, 12, 0, 0, 0, time.UTC)
+
+	out := formatPeriodicSchedule(job, diff, now)
+	if !strings.Contains(out, "Schedule changed") {
+		t.Errorf("expected the schedule change to be described:\n%s", out)
+	}
+}
+
+func TestDescribeCron(t *testing.T) {
+	cases := map[string]string{
+		"0 2 * * *":    "at 02:00",
+		"0 2 * * 1-5":  "at 02:00 on Monday through Friday",
+		"*/15 * * * *": "every 15 minutes",
+		"@daily":       "at 00:00 every day",
+		"0 0 L * *":    "",
+	}
+	for spec, want := range cases {
+		if got := describeCron(spec); got != want {
+			t.Errorf("describeCron(%q) = %q, expected %q", spec, got, want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// policyResultRe matches the result of a Sentinel policy as reported by
+// Nomad, for example "* restrict-images : Result: false (allowed failure
+// based on level)".
+var policyResultRe = regexp.MustCompile(`^\*?\s*(\S+) : Result: (true|false)(.*)$`)
+
+// PolicyResult is the result of a Sentinel policy evaluated against a job.
+type PolicyResult struct {
+	// Name is the name of the policy.
+	Name string
+
+	// Passed is set if the job passed the policy.
+	Passed bool
+
+	// SoftMandatory is set if the policy is soft-mandatory, and so its
+	// failure can be overridden. Otherwise it is hard-mandatory.
+	SoftMandatory bool
+
+	// Details are the rule evaluations Nomad printed for the policy.
+	Details string
+}
+
+// parsePolicyResults extracts the results of the Sentinel policies from the
+// message of a plan error or the warnings of a plan.
+func parsePolicyResults(s string) []*PolicyResult {
+	var out []*PolicyResult
+	var current *PolicyResult
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := policyResultRe.FindStringSubmatch(trimmed); m != nil {
+			current = &PolicyResult{
+				Name:          m[1],
+				Passed:        m[2] == "true",
+				SoftMandatory: strings.Contains(m[3], "allowed failure based on level"),
+			}
+			out = append(out, current)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "*") {
+			// Another error or warning unrelated to the policy.
+			current = nil
+		}
+		if current != nil && trimmed != "" {
+			current.Details = strings.TrimPrefix(current.Details+"\n"+trimmed, "\n")
+		}
+	}
+	return out
+}
+
+// formatPolicyResults produces a string listing the Sentinel policies and,
+// for the failed ones, whether they blocked the job. Overridden is set if the
+// plan succeeded because the soft-mandatory failures were overridden.
+func formatPolicyResults(policies []*PolicyResult, overridden bool) string {
+	out := "[bold]Sentinel Policies:[reset]\n"
+	for _, p := range policies {
+		switch {
+		case p.Passed:
+			out += fmt.Sprintf("[green]- Policy %q passed[reset]\n", p.Name)
+		case !p.SoftMandatory:
+			out += fmt.Sprintf("[red]- Hard-mandatory policy %q failed and blocks the job[reset]\n", p.Name)
+		case overridden:
+			out += fmt.Sprintf("[yellow]- Soft-mandatory policy %q failed and was overridden[reset]\n", p.Name)
+		default:
+			out += fmt.Sprintf("[yellow]- Soft-mandatory policy %q failed and can be overridden[reset]\n", p.Name)
+		}
+		if p.Details != "" {
+			out += indent(p.Details, "    ") + "\n"
+		}
+	}
+	return out
+}
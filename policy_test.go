@@ -0,0 +1,98 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const policyTestMessage = `3 errors occurred:
+	* restrict-images : Result: false (allowed failure based on level)
+
+FALSE - restrict-images:3:1 - Rule "main"
+
+	* require-owner : Result: true
+
+	* no-privileged : Result: false
+
+FALSE - no-privileged:2:1 - Rule "main"
+
+`
+
+func TestParsePolicyResults(t *testing.T) {
+	policies := parsePolicyResults(policyTestMessage)
+	if len(policies) != 3 {
+		t.Fatalf("expected 3 policies, got %d", len(policies))
+	}
+
+	expected := []PolicyResult{
+		{Name: "restrict-images", SoftMandatory: true, Details: `FALSE - restrict-images:3:1 - Rule "main"`},
+		{Name: "require-owner", Passed: true},
+		{Name: "no-privileged", Details: `FALSE - no-privileged:2:1 - Rule "main"`},
+	}
+	for i, p := range policies {
+		if *p != expected[i] {
+			t.Errorf("policy %d is %+v, expected %+v", i, *p, expected[i])
+		}
+	}
+}
+
+func TestFormatPolicyResults(t *testing.T) {
+	out := stripColor(formatPolicyResults(parsePolicyResults(policyTestMessage), false))
+	for _, expected := range []string{
+		`- Soft-mandatory policy "restrict-images" failed and can be overridden`,
+		`- Policy "require-owner" passed`,
+		`- Hard-mandatory policy "no-privileged" failed and blocks the job`,
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("expected %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestPlanErrorPolicies(t *testing.T) {
+	job := testJob("example", "default")
+
+	err := planError(context.Background(), job, errors.New("Unexpected response code: 500 ("+policyTestMessage+")"))
+	var policyErr *PolicyError
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a *PolicyError, got %T: %v", err, err)
+	}
+	if !policyErr.HardMandatory {
+		t.Errorf("expected the failed hard-mandatory policy to block the job")
+	}
+
+	// A passed policy doesn't make the error hard-mandatory.
+	msg := "* require-owner : Result: true\n* restrict-images : Result: false (allowed failure based on level)"
+	err = planError(context.Background(), job, errors.New("Unexpected response code: 500 ("+msg+")"))
+	if !errors.As(err, &policyErr) {
+		t.Fatalf("expected a *PolicyError, got %T: %v", err, err)
+	}
+	if policyErr.HardMandatory {
+		t.Errorf("expected only soft-mandatory failures")
+	}
+	if len(policyErr.Policies) != 2 || !policyErr.Policies[0].Passed {
+		t.Errorf("expected the passed policy to be kept: %+v", policyErr.Policies)
+	}
+}
+
+func TestOutputPlannedJobOverriddenPolicy(t *testing.T) {
+	job, resp := browserTestPlan()
+	resp.Warnings = policyWarnings
+
+	var lines []string
+	print := func(s string) { lines = append(lines, s) }
+	outputPlannedJob(job, resp, print, true, true, nil)
+	out := stripColor(strings.Join(lines, "\n"))
+
+	if !strings.Contains(out, `- Soft-mandatory policy "restrict-images" failed and was overridden`) {
+		t.Errorf("expected the overridden policy:\n%s", out)
+	}
+	if strings.Contains(out, "restrict-images : Result: false") {
+		t.Errorf("expected the policy only under Sentinel Policies:\n%s", out)
+	}
+	if strings.Contains(out, "Job Warnings") {
+		t.Errorf("expected no other warnings:\n%s", out)
+	}
+}
@@ -0,0 +1,131 @@
+package nomaddiffprinter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// DiffQuery answers questions about the changes in a job diff without
+// parsing its rendered output. Changes are addressed by the same paths the
+// flat layout prints, such as TaskGroup[web].Task[app].Config.image.
+type DiffQuery struct {
+	changes []*Change
+}
+
+// QueryDiff returns a DiffQuery over the given job diff.
+func QueryDiff(diff *api.JobDiff) *DiffQuery {
+	q := &DiffQuery{}
+	if diff != nil {
+		q.changes = flattenJobDiff(diff)
+	}
+	return q
+}
+
+// Changes returns every change in the diff.
+func (q *DiffQuery) Changes() []*Change {
+	return q.changes
+}
+
+// Get returns the change at the given path, or nil if the path is unchanged.
+// Objects in a set, such as constraints, share a path; Get returns the first
+// of them and All returns every one.
+func (q *DiffQuery) Get(path string) *Change {
+	for _, c := range q.changes {
+		if c.Path == path {
+			return c
+		}
+	}
+	return nil
+}
+
+// All returns every change at the given path.
+func (q *DiffQuery) All(path string) []*Change {
+	var out []*Change
+	for _, c := range q.changes {
+		if c.Path == path {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Match returns the changes whose path matches the glob pattern. A * matches
+// any sequence of characters within a single path element, ** matches across
+// elements and ? matches a single character. Brackets are matched literally,
+// so TaskGroup[*].Count matches the count of every task group.
+func (q *DiffQuery) Match(pattern string) []*Change {
+	re := globRegexp(pattern)
+
+	var out []*Change
+	for _, c := range q.changes {
+		if re.MatchString(c.Path) {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Matches returns whether any change matches the glob pattern.
+func (q *DiffQuery) Matches(pattern string) bool {
+	re := globRegexp(pattern)
+	for _, c := range q.changes {
+		if re.MatchString(c.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// Paths returns the sorted, unique paths of every change.
+func (q *DiffQuery) Paths() []string {
+	seen := make(map[string]struct{}, len(q.changes))
+	paths := make([]string, 0, len(q.changes))
+	for _, c := range q.changes {
+		if _, ok := seen[c.Path]; ok {
+			continue
+		}
+		seen[c.Path] = struct{}{}
+		paths = append(paths, c.Path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ByAnnotation returns the changes grouped by their annotations. A change
+// with several annotations appears under each of them. Annotations of a task,
+// such as "forces create/destroy update", are found on the change of the task
+// itself rather than on its fields.
+func (q *DiffQuery) ByAnnotation() map[string][]*Change {
+	out := make(map[string][]*Change)
+	for _, c := range q.changes {
+		for _, a := range c.Annotations {
+			out[a] = append(out[a], c)
+		}
+	}
+	return out
+}
+
+// globRegexp converts a path glob into an anchored regular expression.
+func globRegexp(pattern string) *regexp.Regexp {
+	var b strings.Builder
+	b.WriteString("^")
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; {
+		case c == '*' && i+1 < len(runes) && runes[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString(`[^.]*`)
+		case c == '?':
+			b.WriteString(`[^.]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,48 @@
+package nomaddiffprinter
+
+import (
+	"testing"
+)
+
+func TestDiffQueryByAnnotation(t *testing.T) {
+	q := QueryDiff(annotatedTaskDiff())
+
+	changes := q.ByAnnotation()["forces create/destroy update"]
+	if len(changes) != 1 {
+		t.Fatalf("expected one change forcing a create/destroy update, got %d", len(changes))
+	}
+	if c := changes[0]; c.Path != "TaskGroup[web].Task[app]" || c.Type != diffTypeEdited {
+		t.Errorf("unexpected change: %+v", c)
+	}
+}
+
+func TestDiffQueryMatch(t *testing.T) {
+	q := QueryDiff(annotatedTaskDiff())
+
+	cases := map[string]int{
+		"TaskGroup[*].Count":                    1,
+		"TaskGroup[web].Task[*].Config.*":       1,
+		"TaskGroup[web].**":                     3,
+		"TaskGroup[web].Task[app]":              1,
+		"TaskGroup[api].**":                     0,
+		"TaskGroup[web].Task[app].Config.im?ge": 1,
+	}
+	for pattern, want := range cases {
+		if got := len(q.Match(pattern)); got != want {
+			t.Errorf("Match(%q) returned %d changes, expected %d", pattern, got, want)
+		}
+		if got := q.Matches(pattern); got != (want > 0) {
+			t.Errorf("Matches(%q) = %v", pattern, got)
+		}
+	}
+
+	if c := q.Get("TaskGroup[web].Task[app].Config.image"); c == nil || c.New != "redis:7" {
+		t.Errorf("unexpected change: %+v", c)
+	}
+	if c := q.Get("TaskGroup[web].Task[app].Env"); c != nil {
+		t.Errorf("expected no change, got %+v", c)
+	}
+	if got := QueryDiff(nil).Changes(); len(got) != 0 {
+		t.Errorf("expected no changes for a nil diff, got %d", len(got))
+	}
+}
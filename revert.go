@@ -0,0 +1,58 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// PlanRevertAndPrintDiff previews the effect of reverting a job to a prior
+// version. The historical version is planned against the currently registered
+// job and the result is printed the same way as PlanAndPrintDiff. The returned
+// stable flag reports whether the version was marked stable.
+func PlanRevertAndPrintDiff(client *api.Client, jobID string, version uint64, q *api.QueryOptions, output io.Writer) (resp *api.JobPlanResponse, stable bool, err error) {
+	return PlanRevertAndPrintDiffContext(context.Background(), client, jobID, version, q, output)
+}
+
+// PlanRevertAndPrintDiffContext is like PlanRevertAndPrintDiff but the lookup
+// of the job versions and the plan request are bound to ctx.
+func PlanRevertAndPrintDiffContext(ctx context.Context, client *api.Client, jobID string, version uint64, q *api.QueryOptions, output io.Writer) (resp *api.JobPlanResponse, stable bool, err error) {
+	versions, _, _, err := client.Jobs().Versions(jobID, false, q.WithContext(ctx))
+	if err != nil {
+		return nil, false, contextError(ctx, "job versions", err)
+	}
+	job, err := findJobVersion(versions, version)
+	if err != nil {
+		return nil, false, err
+	}
+	if job.Stable != nil {
+		stable = *job.Stable
+	}
+
+	w := &api.WriteOptions{}
+	if q != nil {
+		w.Region = q.Region
+		w.Namespace = q.Namespace
+	}
+
+	opts := &api.PlanOptions{Diff: true}
+	resp, _, err = client.Jobs().PlanOpts(job, opts, w.WithContext(ctx))
+	if err != nil {
+		return nil, stable, planError(ctx, job, err)
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	print(colorize().Color(fmt.Sprintf("[bold]Revert job %q to version %d[reset]", jobID, version)))
+	if stable {
+		print(colorize().Color(fmt.Sprintf("[green]- Version %d is marked stable.[reset]\n", version)))
+	} else {
+		print(colorize().Color(fmt.Sprintf("[yellow]- Version %d is not marked stable.[reset]\n", version)))
+	}
+	outputPlannedJob(job, resp, print, opts.Diff, true, nil)
+
+	return resp, stable, nil
+}
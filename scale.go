@@ -0,0 +1,109 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// PlanScaleAndPrintDiff plans the job with the count of a single task group
+// changed and prints a condensed report containing only the count change and
+// its scheduling consequences. The job passed in is not modified.
+func PlanScaleAndPrintDiff(client *api.Client, job *api.Job, group string, count int, output io.Writer) (resp *api.JobPlanResponse, err error) {
+	return PlanScaleAndPrintDiffContext(context.Background(), client, job, group, count, output)
+}
+
+// PlanScaleAndPrintDiffContext is like PlanScaleAndPrintDiff but the plan
+// request is bound to ctx.
+func PlanScaleAndPrintDiffContext(ctx context.Context, client *api.Client, job *api.Job, group string, count int, output io.Writer) (resp *api.JobPlanResponse, err error) {
+	scaled, err := copyJob(job)
+	if err != nil {
+		return nil, err
+	}
+
+	var tg *api.TaskGroup
+	for _, g := range scaled.TaskGroups {
+		if g.Name != nil && *g.Name == group {
+			tg = g
+		}
+	}
+	if tg == nil {
+		return nil, fmt.Errorf("task group %q not found in job", group)
+	}
+	tg.Count = &count
+
+	resp, err = PlanAndPrintDiffContext(ctx, client, scaled, nil, nil, ioutil.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	print(colorize().Color(formatScale(resp, group, count)))
+	print("")
+
+	return resp, nil
+}
+
+// formatScale produces a string explaining the count change of a task group
+// and the resulting placements, stops, preemptions and failed placements.
+func formatScale(resp *api.JobPlanResponse, group string, count int) string {
+	out := fmt.Sprintf("[bold]Task Group: %q[reset]\n", group)
+	if field := findCountDiff(resp.Diff, group); field != nil {
+		out += fmt.Sprintf("[light_yellow]+/-[reset] Count: %q => %q\n\n", field.Old, field.New)
+	} else {
+		out += fmt.Sprintf("  Count: %q (unchanged)\n\n", fmt.Sprint(count))
+	}
+
+	out += "[bold]Scheduler dry-run:[reset]\n"
+	var updates *api.DesiredUpdates
+	if resp.Annotations != nil {
+		updates = resp.Annotations.DesiredTGUpdates[group]
+	}
+	if updates == nil {
+		updates = &api.DesiredUpdates{}
+	}
+	out += fmt.Sprintf("[green]- %d to place[reset]\n", updates.Place)
+	out += fmt.Sprintf("[red]- %d to stop[reset]\n", updates.Stop)
+	if updates.Preemptions > 0 {
+		out += fmt.Sprintf("[yellow]- %d to preempt[reset]\n", updates.Preemptions)
+	}
+
+	if metrics, ok := resp.FailedTGAllocs[group]; ok {
+		noun := "allocation"
+		if metrics.CoalescedFailures > 0 {
+			noun += "s"
+		}
+		out += fmt.Sprintf("[bold][yellow]- WARNING: Failed to place %d %s:[reset]\n", metrics.CoalescedFailures+1, noun)
+		out += fmt.Sprintf("[yellow]%s[reset]\n", formatAllocMetrics(metrics, false, strings.Repeat(" ", 2)))
+	} else {
+		out += "[bold][green]- All tasks successfully allocated.[reset]\n"
+	}
+
+	out = strings.TrimSuffix(out, "\n")
+	return out
+}
+
+// findCountDiff returns the diff of the count field of a task group, or nil if
+// the count did not change.
+func findCountDiff(diff *api.JobDiff, group string) *api.FieldDiff {
+	if diff == nil {
+		return nil
+	}
+	for _, tg := range diff.TaskGroups {
+		if tg.Name != group {
+			continue
+		}
+		for _, field := range tg.Fields {
+			if field.Name == "Count" {
+				return field
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+const (
+	// sideBySideMarkerWidth is the width reserved for the diff marker so that
+	// names line up regardless of the marker.
+	sideBySideMarkerWidth = 4
+
+	// sideBySideSeparator separates the old and new columns.
+	sideBySideSeparator = " | "
+
+	// minColumnWidth is the narrowest a value column is allowed to be.
+	minColumnWidth = 10
+)
+
+// formatJobDiffSideBySide produces an annotated diff of the job where the old
+// and new values of every field are printed in two columns that fill width. If
+// verbose mode is set, added or deleted task groups and tasks are expanded.
+func formatJobDiffSideBySide(job *api.JobDiff, verbose bool, printOpts *PrintOptions) string {
+	width := printOpts.width()
+	out := fmt.Sprintf("%s[bold]Job: %q[reset]\n", sideBySideMarker(job.Type), job.ID)
+
+	// Only show the job's field and object diffs if the job is edited or
+	// verbose mode is set.
+	if job.Type == "Edited" || verbose {
+		out += sideBySideFieldsAndObjects(job.Fields, job.Objects, 0, width, printOpts)
+	}
+
+	for _, tg := range job.TaskGroups {
+		out += fmt.Sprintf("%s[bold]Task Group: %q[reset]%s\n",
+			sideBySideMarker(tg.Type), tg.Name, formatTaskGroupUpdates(tg.Updates))
+		if tg.Type == "Edited" || verbose {
+			out += sideBySideFieldsAndObjects(tg.Fields, tg.Objects, 2, width, printOpts)
+		}
+
+		for _, task := range tg.Tasks {
+			out += fmt.Sprintf("  %s[bold]Task: %q[reset]", sideBySideMarker(task.Type), task.Name)
+			if len(task.Annotations) != 0 {
+				out += fmt.Sprintf(" (%s)", colorAnnotations(task.Annotations))
+			}
+			out += "\n"
+
+			if task.Type == "None" || ((task.Type == "Deleted" || task.Type == "Added") && !verbose) {
+				continue
+			}
+			out += sideBySideFieldsAndObjects(task.Fields, task.Objects, 4, width, printOpts)
+		}
+	}
+
+	return out
+}
+
+// sideBySideFieldsAndObjects prints fields and objects in two columns.
+// startPrefix is the number of spaces to prefix every line with.
+func sideBySideFieldsAndObjects(fields []*api.FieldDiff, objects []*api.ObjectDiff, startPrefix, width int, printOpts *PrintOptions) string {
+	longestField, _ := getLongestPrefixes(fields, nil)
+
+	var out string
+	for _, field := range fields {
+		out += formatFieldDiffSideBySide(field, startPrefix, longestField, width, printOpts)
+	}
+
+	start := strings.Repeat(" ", startPrefix)
+	for _, object := range objects {
+		out += fmt.Sprintf("%s%s%s {\n", start, sideBySideMarker(object.Type), object.Name)
+		out += sideBySideFieldsAndObjects(object.Fields, object.Objects, startPrefix+sideBySideMarkerWidth, width, printOpts)
+		out += fmt.Sprintf("%s%s}\n", start, strings.Repeat(" ", sideBySideMarkerWidth))
+	}
+
+	return out
+}
+
+// formatFieldDiffSideBySide prints a field with its old value on the left and
+// its new value on the right. Values that don't fit in their column are
+// continued on the following lines, or truncated if the output is configured
+// to truncate. An added field leaves the left column blank and a deleted field
+// leaves the right column blank.
+func formatFieldDiffSideBySide(diff *api.FieldDiff, startPrefix, longestField, width int, printOpts *PrintOptions) string {
+	var old, new string
+	switch diff.Type {
+	case "Added":
+		new = fmt.Sprintf("%q", diff.New)
+	case "Deleted":
+		old = fmt.Sprintf("%q", diff.Old)
+	default:
+		old = fmt.Sprintf("%q", diff.Old)
+		new = fmt.Sprintf("%q", diff.New)
+	}
+
+	// The separator is placed in the middle of the output regardless of the
+	// nesting so that the columns line up across objects.
+	prefixLen := startPrefix + sideBySideMarkerWidth + longestField + 2
+	leftWidth := (width - len(sideBySideSeparator)) / 2
+	oldWidth := leftWidth - prefixLen
+	if oldWidth < minColumnWidth {
+		oldWidth = minColumnWidth
+	}
+	newWidth := width - leftWidth - len(sideBySideSeparator)
+	if newWidth < minColumnWidth {
+		newWidth = minColumnWidth
+	}
+
+	var oldLines, newLines []string
+	if printOpts.overflow() == OverflowTruncate {
+		oldLines = []string{truncateWidth(old, oldWidth)}
+		newLines = []string{truncateWidth(new, newWidth)}
+	} else {
+		oldLines = splitWidth(old, oldWidth)
+		newLines = splitWidth(new, newWidth)
+	}
+	rows := len(oldLines)
+	if len(newLines) > rows {
+		rows = len(newLines)
+	}
+
+	var out string
+	for i := 0; i < rows; i++ {
+		if i == 0 {
+			out += fmt.Sprintf("%s%s%s: %s",
+				strings.Repeat(" ", startPrefix), sideBySideMarker(diff.Type),
+				diff.Name, strings.Repeat(" ", longestField-displayWidth(diff.Name)))
+		} else {
+			out += strings.Repeat(" ", prefixLen)
+		}
+
+		var o, n string
+		if i < len(oldLines) {
+			o = oldLines[i]
+		}
+		if i < len(newLines) {
+			n = newLines[i]
+		}
+		pad := oldWidth - displayWidth(o)
+		if pad < 0 {
+			pad = 0
+		}
+		out += fmt.Sprintf("[red]%s[reset]%s%s[green]%s[reset]",
+			o, strings.Repeat(" ", pad), sideBySideSeparator, n)
+
+		// Color the annotations where possible
+		if i == 0 && len(diff.Annotations) != 0 {
+			out += fmt.Sprintf(" (%s)", colorAnnotations(diff.Annotations))
+		}
+		out += "\n"
+	}
+	return out
+}
+
+// sideBySideMarker returns the colored diff marker padded to a fixed width.
+func sideBySideMarker(diffType string) string {
+	marker, l := getDiffString(diffType)
+	return marker + strings.Repeat(" ", sideBySideMarkerWidth-l)
+}
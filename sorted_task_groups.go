@@ -14,3 +14,29 @@ func sortedTaskGroupFromMetrics(groups map[string]*api.AllocationMetric) []strin
 	sort.Strings(tgs)
 	return tgs
 }
+
+// planTaskGroups returns the sorted names of all task groups mentioned in the
+// plan.
+func planTaskGroups(resp *api.JobPlanResponse) []string {
+	seen := make(map[string]bool)
+	if resp.Diff != nil {
+		for _, tg := range resp.Diff.TaskGroups {
+			seen[tg.Name] = true
+		}
+	}
+	if resp.Annotations != nil {
+		for tg := range resp.Annotations.DesiredTGUpdates {
+			seen[tg] = true
+		}
+	}
+	for tg := range resp.FailedTGAllocs {
+		seen[tg] = true
+	}
+
+	tgs := make([]string, 0, len(seen))
+	for tg := range seen {
+		tgs = append(tgs, tg)
+	}
+	sort.Strings(tgs)
+	return tgs
+}
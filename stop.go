@@ -0,0 +1,158 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+// StopImpact describes what stopping a job would destroy.
+type StopImpact struct {
+	JobID string
+	Purge bool
+
+	// Allocations are the running allocations of the job that would be
+	// stopped, keyed by task group.
+	Allocations map[string][]*api.AllocationListStub
+
+	// Services are the names of the services that would be deregistered.
+	Services []string
+}
+
+// StopPreview lists the allocations and services that would be removed if
+// the job was stopped, and purged if purge is set. The returned exit code
+// follows the conventions of a plan: 1 if allocations would be stopped, 0
+// otherwise.
+func StopPreview(client *api.Client, jobID string, purge bool, q *api.QueryOptions, output io.Writer) (impact *StopImpact, exitCode int, err error) {
+	return StopPreviewContext(context.Background(), client, jobID, purge, q, output)
+}
+
+// StopPreviewContext is like StopPreview but the lookups of the job and its
+// allocations are bound to ctx.
+func StopPreviewContext(ctx context.Context, client *api.Client, jobID string, purge bool, q *api.QueryOptions, output io.Writer) (impact *StopImpact, exitCode int, err error) {
+	q = q.WithContext(ctx)
+	job, _, err := client.Jobs().Info(jobID, q)
+	if err != nil {
+		return nil, 0, contextError(ctx, "job info", err)
+	}
+	allocs, _, err := client.Jobs().Allocations(jobID, false, q)
+	if err != nil {
+		return nil, 0, contextError(ctx, "job allocations", err)
+	}
+
+	impact = &StopImpact{
+		JobID:       jobID,
+		Purge:       purge,
+		Allocations: make(map[string][]*api.AllocationListStub),
+		Services:    jobServices(job),
+	}
+	for _, alloc := range allocs {
+		if alloc.DesiredStatus != api.AllocDesiredStatusRun {
+			continue
+		}
+		if alloc.ClientStatus != api.AllocClientStatusRunning && alloc.ClientStatus != api.AllocClientStatusPending {
+			continue
+		}
+		impact.Allocations[alloc.TaskGroup] = append(impact.Allocations[alloc.TaskGroup], alloc)
+	}
+
+	print := func(s string) {
+		fmt.Fprintln(output, s)
+	}
+	print(colorize().Color("[bold]Stop preview:[reset]"))
+	print(colorize().Color(formatStopImpact(impact)))
+	print("")
+
+	return impact, impact.exitCode(), nil
+}
+
+// exitCode returns 1 if allocations would be stopped and 0 otherwise.
+func (s *StopImpact) exitCode() int {
+	for _, allocs := range s.Allocations {
+		if len(allocs) > 0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// formatStopImpact produces a string explaining what stopping the job would
+// destroy, in the same style as formatDryRun.
+func formatStopImpact(impact *StopImpact) string {
+	var out string
+	if impact.exitCode() == 0 {
+		out = "[bold][green]- No running allocations would be stopped.[reset]\n"
+	} else {
+		out = "[bold][yellow]- WARNING: Running allocations would be stopped.[reset]\n"
+
+		groups := make([]string, 0, len(impact.Allocations))
+		for tg := range impact.Allocations {
+			groups = append(groups, tg)
+		}
+		sort.Strings(groups)
+		for _, tg := range groups {
+			allocs := impact.Allocations[tg]
+			sort.Slice(allocs, func(i, j int) bool { return allocs[i].ID < allocs[j].ID })
+
+			noun := "allocation"
+			if len(allocs) > 1 {
+				noun += "s"
+			}
+			out += fmt.Sprintf("%s[yellow]Task Group %q (%d %s):\n[reset]", strings.Repeat(" ", 2), tg, len(allocs), noun)
+
+			rows := []string{"Alloc ID|Node ID|Node Name|Status"}
+			for _, alloc := range allocs {
+				rows = append(rows, fmt.Sprintf("%s|%s|%s|%s", alloc.ID, alloc.NodeID, alloc.NodeName, alloc.ClientStatus))
+			}
+			out += fmt.Sprintf("[yellow]%s[reset]\n\n", indent(formatList(rows), strings.Repeat(" ", 4)))
+		}
+	}
+
+	if len(impact.Services) > 0 {
+		out += fmt.Sprintf("[yellow]- Services that would be deregistered: %s[reset]\n", strings.Join(impact.Services, ", "))
+	}
+
+	if impact.Purge {
+		out += fmt.Sprintf("[red]- Job %q and its history would be purged.[reset]\n", impact.JobID)
+	}
+
+	out = strings.TrimSuffix(out, "\n")
+	return out
+}
+
+// jobServices returns the sorted, deduplicated names of the services
+// registered by the job's groups and tasks.
+func jobServices(job *api.Job) []string {
+	seen := make(map[string]bool)
+	var services []string
+	add := func(list []*api.Service) {
+		for _, s := range list {
+			if s == nil || seen[s.Name] {
+				continue
+			}
+			seen[s.Name] = true
+			services = append(services, s.Name)
+		}
+	}
+	for _, tg := range job.TaskGroups {
+		add(tg.Services)
+		for _, task := range tg.Tasks {
+			add(task.Services)
+		}
+	}
+	sort.Strings(services)
+	return services
+}
+
+// indent prefixes every line of s with prefix.
+func indent(s, prefix string) string {
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = prefix + line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,114 @@
+package nomaddiffprinter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+var (
+	// taskGroupValidationRe and taskValidationRe match the prefixes Nomad
+	// gives the validation errors of a task group or task.
+	taskGroupValidationRe = regexp.MustCompile(`^Task group (.+?) validation failed:?\s*(.*)$`)
+	taskValidationRe      = regexp.MustCompile(`^Task (.+?) validation failed:?\s*(.*)$`)
+
+	// multierrorHeaderRe matches the header of a list of errors.
+	multierrorHeaderRe = regexp.MustCompile(`^\d+ errors? occurred:\s*(.*)$`)
+)
+
+// validationError is a validation error of a job along with the path of the
+// task group or task it applies to, if any.
+type validationError struct {
+	path    string
+	message string
+}
+
+// validateJob validates the job with the server and prints any validation
+// errors and warnings. A *ValidationError is returned if the job is invalid.
+func validateJob(ctx context.Context, client *api.Client, job *api.Job, print func(string)) error {
+	resp, _, err := client.Jobs().Validate(job, writeOptions(ctx, job))
+	if err != nil {
+		return planError(ctx, job, err)
+	}
+
+	errs := resp.ValidationErrors
+	if len(errs) == 0 && resp.Error != "" {
+		errs = []string{resp.Error}
+	}
+	if len(errs) != 0 {
+		print(colorize().Color(fmt.Sprintf("[bold][red]Job Validation Errors:\n%s[reset]\n",
+			formatValidationErrors(errs))))
+	}
+	if warnings := ParseWarnings(resp.Warnings); len(warnings) > 0 {
+		print(colorize().Color(fmt.Sprintf("[bold][yellow]Job Validation Warnings:\n%s[reset]\n",
+			formatWarnings(warnings))))
+	}
+
+	if len(errs) != 0 {
+		message := resp.Error
+		if message == "" {
+			message = strings.Join(errs, "; ")
+		}
+		return &ValidationError{JobID: stringValue(job.ID), Message: message}
+	}
+	return nil
+}
+
+// formatValidationErrors produces a bulleted list of validation errors, each
+// prefixed by the path of the task group or task it applies to.
+func formatValidationErrors(errs []string) string {
+	var lines []string
+	for _, e := range errs {
+		for _, v := range parseValidationError(e) {
+			if v.path == "" {
+				lines = append(lines, fmt.Sprintf("* %s", v.message))
+			} else {
+				lines = append(lines, fmt.Sprintf("* %s: %s", v.path, v.message))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseValidationError splits a validation error returned by Nomad into its
+// individual errors. Nested errors of task groups and tasks are formatted by
+// Nomad as "Task group web validation failed: 1 error occurred:\n\t* ..." and
+// are attributed to TaskGroup[web] and TaskGroup[web].Task[app] paths.
+func parseValidationError(e string) []*validationError {
+	var out []*validationError
+	var group, task string
+	for _, line := range strings.Split(e, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+
+		for line != "" {
+			if m := taskGroupValidationRe.FindStringSubmatch(line); m != nil {
+				group, task, line = m[1], "", m[2]
+			} else if m := taskValidationRe.FindStringSubmatch(line); m != nil {
+				task, line = m[1], m[2]
+			} else if m := multierrorHeaderRe.FindStringSubmatch(line); m != nil {
+				line = m[1]
+			} else {
+				out = append(out, &validationError{path: validationPath(group, task), message: line})
+				line = ""
+			}
+		}
+	}
+	return out
+}
+
+// validationPath returns the path of a task group or task in the same form
+// as the flat layout.
+func validationPath(group, task string) string {
+	switch {
+	case group == "":
+		return ""
+	case task == "":
+		return fmt.Sprintf("TaskGroup[%s]", group)
+	default:
+		return fmt.Sprintf("TaskGroup[%s].Task[%s]", group, task)
+	}
+}
@@ -0,0 +1,159 @@
+package nomaddiffprinter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// WarningCategory classifies a warning returned by a plan.
+type WarningCategory string
+
+const (
+	// WarningDeprecation is a warning about a deprecated field or feature.
+	WarningDeprecation WarningCategory = "deprecation"
+
+	// WarningUpdate is a warning about a missing or ignored update stanza.
+	WarningUpdate WarningCategory = "update"
+
+	// WarningPolicy is a Sentinel policy failure that was overridden.
+	WarningPolicy WarningCategory = "policy"
+
+	// WarningOther is any warning that doesn't fit another category.
+	WarningOther WarningCategory = "other"
+)
+
+// warningsHeaderRe matches the header Nomad puts before a list of warnings,
+// or of the errors nested in a warning.
+var warningsHeaderRe = regexp.MustCompile(`^\d+ (warning\(s\)|warnings?|errors? occurred):$`)
+
+// nestedWarningsRe matches a warning introducing a nested list, for example
+// `Group "cache" has warnings: 1 error occurred:`.
+var nestedWarningsRe = regexp.MustCompile(`^(.*\S)\s+\d+ errors? occurred:$`)
+
+// PlanWarning is a single warning returned by a plan.
+type PlanWarning struct {
+	Category WarningCategory
+	Message  string
+}
+
+// WarningError is returned when a plan returns warnings of a category that
+// is treated as an error.
+type WarningError struct {
+	JobID    string
+	Warnings []*PlanWarning
+}
+
+func (e *WarningError) Error() string {
+	messages := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		messages[i] = w.Message
+	}
+	return fmt.Sprintf("plan of job %q returned warnings treated as errors: %s",
+		e.JobID, strings.Join(messages, "; "))
+}
+
+// ParseWarnings splits the warnings of a plan, which Nomad joins into a
+// single string, into individual warnings. The warnings nested in another,
+// such as those of a task group, are prefixed with the text of their parent.
+// Each warning is classified and duplicates are removed.
+func ParseWarnings(s string) []*PlanWarning {
+	var messages []string
+	var current []string
+	var prefix string
+	flush := func() {
+		if msg := strings.TrimSpace(strings.Join(current, "\n")); msg != "" {
+			messages = append(messages, msg)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case warningsHeaderRe.MatchString(trimmed):
+			flush()
+		case strings.HasPrefix(trimmed, "* "):
+			flush()
+			nested := strings.TrimLeft(line, " \t") != line
+			if !nested {
+				prefix = ""
+			}
+			msg := strings.TrimPrefix(trimmed, "* ")
+			if m := nestedWarningsRe.FindStringSubmatch(msg); m != nil {
+				prefix = m[1]
+				continue
+			}
+			if warningsHeaderRe.MatchString(msg) {
+				continue
+			}
+			if nested && prefix != "" {
+				msg = prefix + " " + msg
+			}
+			current = append(current, msg)
+		case trimmed != "":
+			current = append(current, strings.TrimRight(line, " \t"))
+		}
+	}
+	flush()
+
+	var out []*PlanWarning
+	seen := make(map[string]bool, len(messages))
+	for _, msg := range messages {
+		if seen[msg] {
+			continue
+		}
+		seen[msg] = true
+		out = append(out, &PlanWarning{Category: warningCategory(msg), Message: msg})
+	}
+	return out
+}
+
+// warningCategory classifies a warning by its message.
+func warningCategory(msg string) WarningCategory {
+	lower := strings.ToLower(msg)
+	switch {
+	case policyResultRe.MatchString(strings.SplitN(msg, "\n", 2)[0]):
+		return WarningPolicy
+	case strings.Contains(lower, "deprecat"):
+		return WarningDeprecation
+	case strings.Contains(lower, "update stanza") || strings.Contains(lower, "update block"):
+		return WarningUpdate
+	default:
+		return WarningOther
+	}
+}
+
+// formatWarnings produces a bulleted list of warnings.
+func formatWarnings(warnings []*PlanWarning) string {
+	lines := make([]string, len(warnings))
+	for i, w := range warnings {
+		lines[i] = "* " + strings.Replace(w.Message, "\n", "\n  ", -1)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// withoutCategory returns the warnings whose category isn't category.
+func withoutCategory(warnings []*PlanWarning, category WarningCategory) []*PlanWarning {
+	var out []*PlanWarning
+	for _, w := range warnings {
+		if w.Category != category {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// warningsAsErrors returns the warnings whose category is in categories.
+func warningsAsErrors(warnings []*PlanWarning, categories []WarningCategory) []*PlanWarning {
+	var out []*PlanWarning
+	for _, w := range warnings {
+		for _, c := range categories {
+			if w.Category == c {
+				out = append(out, w)
+				break
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,98 @@
+package nomaddiffprinter
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	maxParallelWarning = "Update max parallel count is greater than task group count (13 > 3). " +
+		"A destructive change would result in the simultaneous replacement of all allocations."
+
+	// policyWarnings are the warnings of a plan overriding the failure of a
+	// soft-mandatory Sentinel policy.
+	policyWarnings = "1 warning(s):\n\n" +
+		"* 1 error occurred:\n" +
+		"\t* restrict-images : Result: false (allowed failure based on level)\n\n" +
+		"FALSE - restrict-images:3:1 - Rule \"main\"\n" +
+		"  FALSE - restrict-images:4:3 - all job.task_groups as tg\n\n\n"
+)
+
+func TestParseWarnings(t *testing.T) {
+	cases := []struct {
+		name     string
+		warnings string
+		expected []*PlanWarning
+	}{
+		{
+			name:     "none",
+			warnings: "",
+		},
+		{
+			name:     "job warning",
+			warnings: "1 warning(s):\n\n* auto_promote must be true for all groups to enable automatic promotion",
+			expected: []*PlanWarning{
+				{Category: WarningOther, Message: "auto_promote must be true for all groups to enable automatic promotion"},
+			},
+		},
+		{
+			name:     "group warning",
+			warnings: "1 warning(s):\n\n* Group \"cache\" has warnings: 1 error occurred:\n\t* " + maxParallelWarning + "\n\n",
+			expected: []*PlanWarning{
+				{Category: WarningOther, Message: "Group \"cache\" has warnings: " + maxParallelWarning},
+			},
+		},
+		{
+			name: "several group warnings",
+			warnings: "2 warning(s):\n\n" +
+				"* Group \"cache\" has warnings: 2 errors occurred:\n" +
+				"\t* " + maxParallelWarning + "\n" +
+				"\t* Task \"redis\": The update stanza is deprecated\n\n\n" +
+				"* auto_promote must be true for all groups to enable automatic promotion",
+			expected: []*PlanWarning{
+				{Category: WarningOther, Message: "Group \"cache\" has warnings: " + maxParallelWarning},
+				{Category: WarningDeprecation, Message: "Group \"cache\" has warnings: Task \"redis\": The update stanza is deprecated"},
+				{Category: WarningOther, Message: "auto_promote must be true for all groups to enable automatic promotion"},
+			},
+		},
+		{
+			name:     "policy",
+			warnings: policyWarnings,
+			expected: []*PlanWarning{{
+				Category: WarningPolicy,
+				Message: "restrict-images : Result: false (allowed failure based on level)\n" +
+					"FALSE - restrict-images:3:1 - Rule \"main\"\n" +
+					"  FALSE - restrict-images:4:3 - all job.task_groups as tg",
+			}},
+		},
+		{
+			name:     "duplicates",
+			warnings: "2 warning(s):\n\n* The update stanza is deprecated\n* The update stanza is deprecated",
+			expected: []*PlanWarning{
+				{Category: WarningDeprecation, Message: "The update stanza is deprecated"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := ParseWarnings(c.warnings)
+			if !reflect.DeepEqual(got, c.expected) {
+				t.Errorf("unexpected warnings:")
+				for _, w := range got {
+					t.Logf("%s: %q", w.Category, w.Message)
+				}
+			}
+		})
+	}
+}
+
+func TestWarningsAsErrorsIgnoresHeaders(t *testing.T) {
+	warnings := ParseWarnings("1 warning(s):\n\n* Group \"cache\" has warnings: 1 error occurred:\n\t* " + maxParallelWarning + "\n\n")
+	if got := warningsAsErrors(warnings, []WarningCategory{WarningOther}); len(got) != 1 {
+		t.Errorf("expected a single warning treated as an error, got %d", len(got))
+	}
+	if got := warningsAsErrors(warnings, []WarningCategory{WarningDeprecation}); len(got) != 0 {
+		t.Errorf("expected no warnings treated as errors, got %d", len(got))
+	}
+}
@@ -0,0 +1,127 @@
+package nomaddiffprinter
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+	"github.com/mitchellh/colorstring"
+	"github.com/rivo/uniseg"
+)
+
+// colorTagRe matches the annotations colorstring replaces with colors.
+var colorTagRe = regexp.MustCompile(`(?i)\[[a-z0-9_-]+\]`)
+
+// displayWidth returns the number of terminal columns s occupies once its
+// color annotations are removed. Wide characters such as CJK and emoji count
+// as two columns.
+func displayWidth(s string) int {
+	return runewidth.StringWidth(stripColor(s))
+}
+
+// graphemes splits s into user-perceived characters so that combined
+// characters are never separated when cutting a string.
+func graphemes(s string) []string {
+	var out []string
+	g := uniseg.NewGraphemes(s)
+	for g.Next() {
+		out = append(out, g.Str())
+	}
+	return out
+}
+
+// colorTokens splits s into its color annotations and the user-perceived
+// characters between them.
+func colorTokens(s string) []string {
+	var out []string
+	var last int
+	for _, m := range colorTagRe.FindAllStringIndex(s, -1) {
+		if !isColorTag(s[m[0]:m[1]]) {
+			continue
+		}
+		out = append(out, graphemes(s[last:m[0]])...)
+		out = append(out, s[m[0]:m[1]])
+		last = m[1]
+	}
+	return append(out, graphemes(s[last:])...)
+}
+
+// isColorTag returns whether the token is a color annotation.
+func isColorTag(token string) bool {
+	if len(token) < 3 || token[0] != '[' {
+		return false
+	}
+	_, ok := colorstring.DefaultColors[token[1:len(token)-1]]
+	return ok
+}
+
+// colorTags returns the color annotations of s.
+func colorTags(s string) string {
+	var out string
+	for _, token := range colorTokens(s) {
+		if isColorTag(token) {
+			out += token
+		}
+	}
+	return out
+}
+
+// cutWidth splits s after the last character that fits in width columns.
+// Color annotations take no columns and are never split.
+func cutWidth(s string, width int) (head, tail string) {
+	var w, i int
+	for _, c := range colorTokens(s) {
+		if isColorTag(c) {
+			i += len(c)
+			continue
+		}
+		cw := runewidth.StringWidth(c)
+		if w+cw > width {
+			break
+		}
+		w += cw
+		i += len(c)
+	}
+	return s[:i], s[i:]
+}
+
+// formatColumns aligns rows of cells separated by "|" into columns joined by
+// glue, padding by display width so that wide characters and color
+// annotations don't shift the columns. Empty cells are printed as "<none>"
+// and the last column is not padded.
+func formatColumns(in []string, glue string) string {
+	rows := make([][]string, len(in))
+	var widths []int
+	for i, line := range in {
+		cells := strings.Split(line, "|")
+		for j, cell := range cells {
+			cell = strings.TrimSpace(cell)
+			if cell == "" {
+				cell = "<none>"
+			}
+			cells[j] = cell
+
+			w := displayWidth(cell)
+			if j >= len(widths) {
+				widths = append(widths, w)
+			} else if w > widths[j] {
+				widths[j] = w
+			}
+		}
+		rows[i] = cells
+	}
+
+	lines := make([]string, len(rows))
+	for i, cells := range rows {
+		var line string
+		for j, cell := range cells {
+			if j == len(cells)-1 {
+				line += cell
+				continue
+			}
+			line += cell + strings.Repeat(" ", widths[j]-displayWidth(cell)) + glue
+		}
+		lines[i] = line
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,126 @@
+package nomaddiffprinter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/nomad/api"
+)
+
+func TestDisplayWidth(t *testing.T) {
+	cases := []struct {
+		s        string
+		expected int
+	}{
+		{"", 0},
+		{"image", 5},
+		{"[red]image[reset]", 5},
+		{"[bold][green]+[reset] ", 2},
+		{"日本語", 6},
+		{"[yellow]日本語[reset]", 6},
+		{"🚀", 2},
+		{"👍🏽", 2},
+		{"café", 4},
+		{"cafe\u0301", 4},
+	}
+	for _, c := range cases {
+		if got := displayWidth(c.s); got != c.expected {
+			t.Errorf("displayWidth(%q) = %d, expected %d", c.s, got, c.expected)
+		}
+	}
+}
+
+func TestCutWidth(t *testing.T) {
+	cases := []struct {
+		s          string
+		width      int
+		head, tail string
+	}{
+		{"image", 10, "image", ""},
+		{"image", 3, "ima", "ge"},
+		{"image", 0, "", "image"},
+		{"日本語", 3, "日", "本語"},
+		{"日本語", 4, "日本", "語"},
+		{"a🚀b", 2, "a", "🚀b"},
+		{"a🚀b", 3, "a🚀", "b"},
+		{"cafe\u0301s", 4, "cafe\u0301", "s"},
+		{"👍🏽x", 1, "", "👍🏽x"},
+		{"[green]image[reset]", 3, "[green]ima", "ge[reset]"},
+		{"[green]image[reset]", 5, "[green]image[reset]", ""},
+		{"no [green]changes", 3, "no [green]", "changes"},
+		{"[tag]image", 3, "[ta", "g]image"},
+	}
+	for _, c := range cases {
+		head, tail := cutWidth(c.s, c.width)
+		if head != c.head || tail != c.tail {
+			t.Errorf("cutWidth(%q, %d) = %q, %q, expected %q, %q",
+				c.s, c.width, head, tail, c.head, c.tail)
+		}
+	}
+}
+
+func TestFormatColumns(t *testing.T) {
+	out := formatColumns([]string{
+		"Name|Status|Node",
+		"日本語|[green]running[reset]|node-1",
+		"🚀|pending|",
+		"web|[red]failed[reset]|node-2",
+	}, " ")
+
+	lines := strings.Split(stripColor(out), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected 4 lines, got %d:\n%s", len(lines), out)
+	}
+
+	// Every column starts at the same display column on every line.
+	for _, column := range []string{"Status", "Node"} {
+		start := displayWidth(lines[0][:strings.Index(lines[0], column)])
+		for _, line := range lines[1:] {
+			cells := strings.Fields(line)
+			i := 1
+			if column == "Node" {
+				i = 2
+			}
+			if got := displayWidth(line[:strings.Index(line, cells[i])]); got != start {
+				t.Errorf("column %q starts at %d, expected %d:\n%s", column, got, start, strings.Join(lines, "\n"))
+			}
+		}
+	}
+
+	if !strings.HasSuffix(lines[2], "<none>") {
+		t.Errorf("expected an empty cell to be printed as <none>: %q", lines[2])
+	}
+	if strings.HasSuffix(lines[1], " ") {
+		t.Errorf("the last column is padded: %q", lines[1])
+	}
+}
+
+func TestAlignedFieldsWideNames(t *testing.T) {
+	fields := []*api.FieldDiff{
+		{Type: "Edited", Name: "image", Old: "a", New: "b"},
+		{Type: "Added", Name: "日本語", New: "c"},
+		{Type: "Deleted", Name: "🚀", Old: "d"},
+		{Type: "Added", Name: "[red]colored[reset]", New: "e"},
+	}
+	longestField, longestMarker := getLongestPrefixes(fields, nil)
+	if longestField != 7 || longestMarker != 4 {
+		t.Errorf("getLongestPrefixes = %d, %d, expected 7, 4", longestField, longestMarker)
+	}
+
+	out := stripColor(alignedFieldAndObjects(fields, nil, 2, longestField, longestMarker, nil))
+	lines := strings.Split(out, "\n")
+	if len(lines) != len(fields) {
+		t.Fatalf("expected %d lines, got %d:\n%s", len(fields), len(lines), out)
+	}
+
+	// The values line up regardless of the width of the names.
+	var start int
+	for i, line := range lines {
+		col := displayWidth(line[:strings.Index(line, `"`)])
+		if i == 0 {
+			start = col
+		} else if col != start {
+			t.Errorf("value of line %d starts at column %d, expected %d:\n%s", i, col, start, out)
+		}
+	}
+}